@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// numbered returns the lines 1 to n, replacing line i with changes[i]
+func numbered(n int, changes map[int]string) string {
+	var lines []string
+	for i := 1; i <= n; i++ {
+		if c, ok := changes[i]; ok {
+			lines = append(lines, c)
+		} else {
+			lines = append(lines, fmt.Sprint(i))
+		}
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// Ensure diffs are printed in unified format with three lines of context.
+func TestUnifiedDiff(t *testing.T) {
+	var tests = []struct {
+		name string
+		a, b string
+		exp  string
+	}{
+		{
+			name: "single change",
+			a:    "a\nb\nc\n",
+			b:    "a\nx\nc\n",
+			exp:  "--- f.sol.orig\n+++ f.sol\n@@ -1,3 +1,3 @@\n a\n-b\n+x\n c\n",
+		},
+		{
+			name: "insertion at end",
+			a:    "a\nb\n",
+			b:    "a\nb\nc\n",
+			exp:  "--- f.sol.orig\n+++ f.sol\n@@ -1,2 +1,3 @@\n a\n b\n+c\n",
+		},
+		{
+			name: "distant changes",
+			a:    numbered(10, nil),
+			b:    numbered(10, map[int]string{1: "x", 10: "y"}),
+			exp: "--- f.sol.orig\n+++ f.sol\n" +
+				"@@ -1,4 +1,4 @@\n-1\n+x\n 2\n 3\n 4\n" +
+				"@@ -7,4 +7,4 @@\n 7\n 8\n 9\n-10\n+y\n",
+		},
+		{
+			name: "close changes",
+			a:    numbered(10, nil),
+			b:    numbered(10, map[int]string{2: "x", 7: "y"}),
+			exp: "--- f.sol.orig\n+++ f.sol\n" +
+				"@@ -1,10 +1,10 @@\n 1\n-2\n+x\n 3\n 4\n 5\n 6\n-7\n+y\n 8\n 9\n 10\n",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := unifiedDiff("f.sol", []byte(tt.a), []byte(tt.b)); got != tt.exp {
+			t.Errorf("%s: expected\n%s\ngot\n%s", tt.name, tt.exp, got)
+		}
+	}
+}
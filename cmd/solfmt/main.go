@@ -0,0 +1,142 @@
+// Command solfmt formats Solidity source files.
+//
+// Without flags it prints the formatted source to standard output. Given a
+// directory it formats all .sol files in it recursively, without paths it
+// reads standard input.
+//
+// Usage:
+//
+//	solfmt [flags] [path ...]
+//
+// The flags are:
+//
+//	-d	display diffs instead of rewriting files
+//	-l	list files whose formatting differs from solfmt's
+//	-w	write result to (source) file instead of stdout
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	solparse "github.com/johnkozan/go-solparse"
+)
+
+var (
+	list  = flag.Bool("l", false, "list files whose formatting differs from solfmt's")
+	write = flag.Bool("w", false, "write result to (source) file instead of stdout")
+	diff  = flag.Bool("d", false, "display diffs instead of rewriting files")
+
+	exitCode = 0
+)
+
+func report(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	exitCode = 2
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: solfmt [flags] [path ...]\n")
+	flag.PrintDefaults()
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		if *write {
+			report(fmt.Errorf("error: cannot use -w with standard input"))
+		} else if err := processFile("<standard input>", os.Stdin, os.Stdout); err != nil {
+			report(err)
+		}
+		os.Exit(exitCode)
+	}
+
+	for _, path := range flag.Args() {
+		info, err := os.Stat(path)
+		if err != nil {
+			report(err)
+			continue
+		}
+		if info.IsDir() {
+			walkDir(path)
+		} else if err := processFile(path, nil, os.Stdout); err != nil {
+			report(err)
+		}
+	}
+	os.Exit(exitCode)
+}
+
+func walkDir(dir string) {
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && strings.HasSuffix(info.Name(), ".sol") {
+			if err := processFile(path, nil, os.Stdout); err != nil {
+				report(err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		report(err)
+	}
+}
+
+// processFile formats the file read from in, or filename if in is nil, and
+// writes the result to out as selected by the flags
+func processFile(filename string, in io.Reader, out io.Writer) error {
+	if in == nil {
+		f, err := os.Open(filename)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	src, err := ioutil.ReadAll(in)
+	if err != nil {
+		return err
+	}
+
+	res, err := solparse.Format(src)
+	if err != nil {
+		return fmt.Errorf("%s: %s", filename, err)
+	}
+
+	if bytes.Equal(src, res) {
+		if !*list && !*write && !*diff {
+			_, err = out.Write(res)
+		}
+		return err
+	}
+
+	if *list {
+		fmt.Fprintln(out, filename)
+	}
+	if *write {
+		info, err := os.Stat(filename)
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(filename, res, info.Mode().Perm()); err != nil {
+			return err
+		}
+	}
+	if *diff {
+		fmt.Fprintf(out, "diff %s solfmt/%s\n", filename, filename)
+		io.WriteString(out, unifiedDiff(filename, src, res))
+	}
+	if !*list && !*write && !*diff {
+		_, err = out.Write(res)
+	}
+	return err
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	formatted   = "contract c {\n    uint x;\n}\n"
+	unformatted = "contract c{uint x;}"
+)
+
+// setFlags sets the output flags for the duration of a test.
+func setFlags(t *testing.T, l, w, d bool) {
+	oldList, oldWrite, oldDiff := *list, *write, *diff
+	*list, *write, *diff = l, w, d
+	t.Cleanup(func() { *list, *write, *diff = oldList, oldWrite, oldDiff })
+}
+
+// Ensure the formatted source is printed without flags.
+func TestProcessFile(t *testing.T) {
+	setFlags(t, false, false, false)
+	for _, src := range []string{formatted, unformatted} {
+		var out bytes.Buffer
+		if err := processFile("c.sol", strings.NewReader(src), &out); err != nil {
+			t.Fatal(err)
+		}
+		if out.String() != formatted {
+			t.Errorf("expected\n%s\ngot\n%s", formatted, out.String())
+		}
+	}
+}
+
+// Ensure -l lists only the files whose formatting differs.
+func TestProcessFile_List(t *testing.T) {
+	setFlags(t, true, false, false)
+	dir := t.TempDir()
+	good, bad := filepath.Join(dir, "good.sol"), filepath.Join(dir, "bad.sol")
+	if err := ioutil.WriteFile(good, []byte(formatted), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(bad, []byte(unformatted), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	for _, path := range []string{good, bad} {
+		if err := processFile(path, nil, &out); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if exp := bad + "\n"; out.String() != exp {
+		t.Errorf("expected %q got %q", exp, out.String())
+	}
+
+	// -l alone leaves files untouched
+	src, err := ioutil.ReadFile(bad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(src) != unformatted {
+		t.Errorf("expected %s to be unchanged, got %q", bad, src)
+	}
+}
+
+// Ensure -w rewrites the file and prints nothing.
+func TestProcessFile_Write(t *testing.T) {
+	setFlags(t, false, true, false)
+	path := filepath.Join(t.TempDir(), "c.sol")
+	if err := ioutil.WriteFile(path, []byte(unformatted), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := processFile(path, nil, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("expected no output, got %q", out.String())
+	}
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(src) != formatted {
+		t.Errorf("expected\n%s\ngot\n%s", formatted, src)
+	}
+}
+
+// Ensure -d prints a diff against the formatted source.
+func TestProcessFile_Diff(t *testing.T) {
+	setFlags(t, false, false, true)
+	var out bytes.Buffer
+	if err := processFile("c.sol", strings.NewReader(unformatted+"\n"), &out); err != nil {
+		t.Fatal(err)
+	}
+	exp := "diff c.sol solfmt/c.sol\n--- c.sol.orig\n+++ c.sol\n" +
+		"@@ -1,1 +1,3 @@\n-contract c{uint x;}\n+contract c {\n+    uint x;\n+}\n"
+	if out.String() != exp {
+		t.Errorf("expected\n%s\ngot\n%s", exp, out.String())
+	}
+
+	out.Reset()
+	if err := processFile("c.sol", strings.NewReader(formatted), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("expected no diff for formatted source, got %q", out.String())
+	}
+}
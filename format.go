@@ -0,0 +1,189 @@
+package solparse
+
+import (
+	"bytes"
+	"sort"
+)
+
+// Format parses src and prints it in the canonical style. Formatting is
+// idempotent, formatting the result again does not change it
+func Format(src []byte) ([]byte, error) {
+	p := NewParserWithComments(bytes.NewReader(src))
+	su, err := p.Parse()
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err = Print(&buf, su); err != nil {
+		return nil, err
+	}
+	if len(p.innerComments) == 0 {
+		return buf.Bytes(), nil
+	}
+	return insertComments(src, buf.Bytes(), p.innerComments), nil
+}
+
+// commentInsertion is a comment placed next to the output token at offset
+type commentInsertion struct {
+	offset   int
+	text     string
+	trailing bool // after the token ending at offset, else before the token
+	endsLine bool // nothing may follow on the line of the comment
+}
+
+// insertComments adds the comments inside of statements and declarations of
+// src to out, the printed src. A comment on the line of the token before it
+// trails that token, other comments precede the token after them
+func insertComments(src, out []byte, comments []Span) []byte {
+	srcToks, outToks := scanTokens(src), scanTokens(out)
+	match := matchTokens(tokenKeys(srcToks), tokenKeys(outToks))
+
+	var ins []commentInsertion
+	for _, c := range comments {
+		next := sort.Search(len(srcToks), func(i int) bool { return srcToks[i].span.Start >= c.End })
+		lineStart := bytes.LastIndexByte(src[:c.Start], '\n') + 1
+		lineEnd := bytes.IndexByte(src[c.End:], '\n')
+		if lineEnd < 0 {
+			lineEnd = len(src) - c.End
+		}
+		in := commentInsertion{
+			text:     string(src[c.Start:c.End]),
+			trailing: len(bytes.TrimSpace(src[lineStart:c.Start])) > 0,
+			endsLine: bytes.HasPrefix(src[c.Start:], []byte("//")) ||
+				len(bytes.TrimSpace(src[c.End:c.End+lineEnd])) == 0,
+		}
+
+		// anchor at the nearest token which is in the output, preferring
+		// the side the comment is attached to
+		before, after := -1, -1
+		for i := next - 1; i >= 0 && before < 0; i-- {
+			before = match[i]
+		}
+		for i := next; i < len(srcToks) && after < 0; i++ {
+			after = match[i]
+		}
+		if before < 0 || (after >= 0 && !in.trailing) {
+			in.trailing = false
+			in.offset = outToks[after].span.Start
+		} else {
+			in.trailing = true
+			in.offset = outToks[before].span.End
+		}
+		ins = append(ins, in)
+	}
+	sort.SliceStable(ins, func(i, j int) bool { return ins[i].offset < ins[j].offset })
+
+	var buf bytes.Buffer
+	last := 0
+	for _, in := range ins {
+		buf.Write(out[last:in.offset])
+		last = in.offset
+		indent := lineIndent(out, in.offset)
+		if in.trailing {
+			buf.WriteString(" " + in.text)
+			if in.endsLine {
+				rest := last
+				for rest < len(out) && (out[rest] == ' ' || out[rest] == '\t') {
+					rest++
+				}
+				if rest < len(out) && out[rest] != '\n' {
+					// continue the line below the comment
+					buf.WriteString("\n" + indent + "    ")
+				}
+				last = rest
+			}
+			continue
+		}
+		b := buf.Bytes()
+		if len(bytes.TrimSpace(b[bytes.LastIndexByte(b, '\n')+1:])) > 0 {
+			// break the line in front of the comment
+			buf.Truncate(len(bytes.TrimRight(b, " \t")))
+			indent += "    "
+			buf.WriteString("\n" + indent)
+		}
+		buf.WriteString(in.text)
+		if in.endsLine {
+			buf.WriteString("\n" + indent)
+		} else {
+			buf.WriteString(" ")
+		}
+	}
+	buf.Write(out[last:])
+	return buf.Bytes()
+}
+
+type scannedToken struct {
+	tok  Token
+	span Span
+	lit  string
+}
+
+// scanTokens returns the tokens of src up to and including EOS
+func scanTokens(src []byte) []scannedToken {
+	var toks []scannedToken
+	s := NewScanner(bytes.NewReader(src))
+	for {
+		tok, span, lit := s.Scan()
+		toks = append(toks, scannedToken{tok, span, lit})
+		if tok == EOS {
+			return toks
+		}
+	}
+}
+
+// tokenKeys returns the strings compared to match tokens. Strings compare by
+// kind only since printing normalizes them
+func tokenKeys(toks []scannedToken) []string {
+	keys := make([]string, len(toks))
+	for i, t := range toks {
+		switch t.tok {
+		case StringLiteral, HexStringLiteral, UnicodeStringLiteral:
+			keys[i] = t.tok.String()
+		default:
+			keys[i] = t.tok.String() + " " + t.lit
+		}
+	}
+	return keys
+}
+
+// matchTokens pairs the equal tokens of a and b, which differ in few places
+// only, and returns the index in b of each token of a or -1
+func matchTokens(a, b []string) []int {
+	const window = 8
+	match := make([]int, len(a))
+	for i := range match {
+		match[i] = -1
+	}
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		if a[i] == b[j] {
+			match[i] = j
+			i, j = i+1, j+1
+			continue
+		}
+		// skip the fewest tokens on either side to get back in step
+		di, dj := 1, 1
+	search:
+		for d := 1; d <= window; d++ {
+			for x := 0; x <= d; x++ {
+				if i+x < len(a) && j+d-x < len(b) && a[i+x] == b[j+d-x] {
+					di, dj = x, d-x
+					break search
+				}
+			}
+		}
+		i, j = i+di, j+dj
+	}
+	return match
+}
+
+// lineIndent returns the indentation of the line of b containing offset
+func lineIndent(b []byte, offset int) string {
+	start := bytes.LastIndexByte(b[:offset], '\n') + 1
+	end := start
+	for end < len(b) && (b[end] == ' ' || b[end] == '\t') {
+		end++
+	}
+	return string(b[start:end])
+}
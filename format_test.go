@@ -0,0 +1,215 @@
+package solparse
+
+import (
+	"bytes"
+	"testing"
+)
+
+// Ensure sources are printed in the canonical style.
+func TestFormat(t *testing.T) {
+	var tests = []struct {
+		name   string
+		source string
+		exp    string
+	}{
+		{"empty", "", ""},
+
+		{
+			name:   "legacy fallback and constructor",
+			source: "contract c { function() payable {} function c() {} fallback() external {} }",
+			exp: `contract c {
+    function() payable {}
+
+    function c() {}
+
+    fallback() external {}
+}
+`,
+		},
+
+		{
+			name:   "tight operators before prefix operators",
+			source: "contract c{function f(){x=y==a- -b;x=y==a- --b;x=y==a+ ++b;x=y==a- -b*c;x=y==a*-b;}}",
+			exp: `contract c {
+    function f() {
+        x = y == a - -b;
+        x = y == a - --b;
+        x = y == a + ++b;
+        x = y == a - -b*c;
+        x = y == a*-b;
+    }
+}
+`,
+		},
+
+		{
+			name:   "spacing and operator precedence",
+			source: "contract c{function f(){x=a*b+c**2-(d+e)*f;y=2**3;z=- -x;delete m[k];(a, , c)=g();}}",
+			exp: `contract c {
+    function f() {
+        x = a*b + c**2 - (d + e)*f;
+        y = 2 ** 3;
+        z = - -x;
+        delete m[k];
+        (a, , c) = g();
+    }
+}
+`,
+		},
+
+		{
+			name: "comments",
+			source: `// SPDX-License-Identifier: MIT
+uint constant X = 1; // one
+contract c { // c
+	/// doc
+	uint a;   /* a */
+	function f() {
+		// first
+		a = 1; // set
+		/* last */
+	}
+}
+`,
+			exp: `// SPDX-License-Identifier: MIT
+uint constant X = 1; // one
+
+contract c { // c
+    /// doc
+    uint a; /* a */
+
+    function f() {
+        // first
+        a = 1; // set
+        /* last */
+    }
+}
+`,
+		},
+
+		{
+			name:   "comments in declarations and expressions",
+			source: "contract c { uint /* t */ x; function f(uint a /* amount */, uint b) /* h */ { x = a /*c*/ + b; if (a) // c\n x; else y; x = a +\n // own line\n b; } }",
+			exp: `contract c {
+    uint /* t */ x;
+
+    function f(uint a /* amount */, uint b) /* h */ {
+        x = a /*c*/ + b;
+        if (a) // c
+            x;
+        else
+            y;
+        x = a +
+            // own line
+            b;
+    }
+}
+`,
+		},
+
+		{
+			name:   "long parameter list",
+			source: "contract c { function transfer(address recipient, uint256 amount, bytes calldata data, uint256 deadline) public returns (bool) {} }",
+			exp: `contract c {
+    function transfer(
+        address recipient,
+        uint256 amount,
+        bytes calldata data,
+        uint256 deadline
+    ) public returns (bool) {}
+}
+`,
+		},
+
+		{
+			name:   "blank lines between functions only",
+			source: "contract c { uint a; uint b; function f(); function g() {} modifier m { _; } }",
+			exp: `contract c {
+    uint a;
+    uint b;
+    function f();
+
+    function g() {}
+
+    modifier m {
+        _;
+    }
+}
+`,
+		},
+
+		{
+			name:   "control flow",
+			source: "contract c { function f() { if (a) return; else if (b) { c(); } else { d(); } for (;;) {} do x--; while (x > 0); } }",
+			exp: `contract c {
+    function f() {
+        if (a)
+            return;
+        else if (b) {
+            c();
+        } else {
+            d();
+        }
+        for (;;) {}
+        do
+            x--;
+        while (x > 0);
+    }
+}
+`,
+		},
+
+		{
+			name:   "literals",
+			source: `contract c { function f() { s = 'it''s' "\x01"; h = hex"00_ff" hex'01'; u = unicode"☃	"; t = 1 days; } }`,
+			exp: `contract c {
+    function f() {
+        s = "its\x01";
+        h = hex"00ff01";
+        u = unicode"☃\t";
+        t = 1 days;
+    }
+}
+`,
+		},
+
+		{
+			name:   "assembly",
+			source: `contract c { function f() { assembly { let x := 0 for { let i := 0 } lt(i, 10) { i := add(i, 1) } { x := add(x, i) } switch x case 0 { revert(0, 0) } default {} } } }`,
+			exp: `contract c {
+    function f() {
+        assembly {
+            let x := 0
+            for { let i := 0 } lt(i, 10) { i := add(i, 1) } {
+                x := add(x, i)
+            }
+            switch x
+            case 0 {
+                revert(0, 0)
+            }
+            default {}
+        }
+    }
+}
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		out, err := Format([]byte(tt.source))
+		if err != nil {
+			t.Errorf("%s: unexpected error %s", tt.name, err)
+			continue
+		}
+		if string(out) != tt.exp {
+			t.Errorf("%s: expected\n%s\ngot\n%s", tt.name, tt.exp, out)
+			continue
+		}
+		again, err := Format(out)
+		if err != nil {
+			t.Errorf("%s: formatted source does not parse: %s", tt.name, err)
+		} else if !bytes.Equal(again, out) {
+			t.Errorf("%s: formatting is not idempotent, got\n%s", tt.name, again)
+		}
+	}
+}
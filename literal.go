@@ -0,0 +1,112 @@
+package solparse
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrNotInteger is returned when an integer is required but the number
+	// literal has a fractional part, e.g. 1.5 or 1e-3
+	ErrNotInteger = errors.New("number literal is not an integer")
+	// ErrOverflow is returned when a number literal does not fit into 256 bits
+	ErrOverflow = errors.New("number literal does not fit into 256 bits")
+)
+
+// maxExponent bounds the exponent of scientific notation so evaluating a
+// literal like 1e1000000000 cannot exhaust memory
+const maxExponent = 4096
+
+var maxUint256 = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+
+var subDenominationMultipliers = map[Token]int64{
+	SubWei:    1,
+	SubGwei:   1e9,
+	SubSzabo:  1e12,
+	SubFinney: 1e15,
+	SubEther:  1e18,
+	SubSecond: 1,
+	SubMinute: 60,
+	SubHour:   3600,
+	SubDay:    86400,
+	SubWeek:   604800,
+	SubYear:   31536000,
+}
+
+// RationalValue returns the exact value of a number literal with its
+// subdenomination applied, e.g. 1.5 ether is 1500000000000000000
+func (l Literal) RationalValue() (*big.Rat, error) {
+	if l.Kind != NumberLiteralKind {
+		return nil, fmt.Errorf("Expected number literal, got '%s'", l.Value)
+	}
+	lit := strings.Replace(l.Value, "_", "", -1)
+
+	r := new(big.Rat)
+	if strings.HasPrefix(lit, "0x") || strings.HasPrefix(lit, "0X") {
+		if l.SubDenomination != EOS {
+			return nil, errors.New("Hexadecimal numbers cannot be used with unit denominations")
+		}
+		i, ok := new(big.Int).SetString(lit[2:], 16)
+		if !ok {
+			return nil, fmt.Errorf("Invalid number literal '%s'", l.Value)
+		}
+		return r.SetInt(i), nil
+	}
+
+	mantissa, exponent := lit, 0
+	if pos := strings.IndexAny(lit, "eE"); pos >= 0 {
+		mantissa = lit[:pos]
+		var err error
+		exponent, err = strconv.Atoi(lit[pos+1:])
+		if err != nil || exponent > maxExponent || exponent < -maxExponent {
+			return nil, fmt.Errorf("Invalid exponent in number literal '%s'", l.Value)
+		}
+	}
+	if _, ok := r.SetString(mantissa); !ok || mantissa == "" {
+		return nil, fmt.Errorf("Invalid number literal '%s'", l.Value)
+	}
+
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs(exponent))), nil)
+	if exponent < 0 {
+		r.Quo(r, new(big.Rat).SetInt(scale))
+	} else {
+		r.Mul(r, new(big.Rat).SetInt(scale))
+	}
+
+	if l.SubDenomination != EOS {
+		multiplier, ok := subDenominationMultipliers[l.SubDenomination]
+		if !ok {
+			return nil, fmt.Errorf("Invalid subdenomination '%s'", l.SubDenomination)
+		}
+		r.Mul(r, new(big.Rat).SetInt64(multiplier))
+	}
+	return r, nil
+}
+
+// IntegerValue returns the value of a number literal as an integer. It
+// returns ErrNotInteger if the value has a fractional part and ErrOverflow
+// if it does not fit into a uint256
+func (l Literal) IntegerValue() (*big.Int, error) {
+	r, err := l.RationalValue()
+	if err != nil {
+		return nil, err
+	}
+	if !r.IsInt() {
+		return nil, ErrNotInteger
+	}
+	i := new(big.Int).Set(r.Num())
+	if i.Cmp(maxUint256) > 0 {
+		return nil, ErrOverflow
+	}
+	return i, nil
+}
+
+func abs(i int) int {
+	if i < 0 {
+		return -i
+	}
+	return i
+}
@@ -0,0 +1,57 @@
+package solparse
+
+import (
+	"testing"
+)
+
+// Ensure number literals evaluate to their exact values.
+func TestLiteral_Value(t *testing.T) {
+	var tests = []struct {
+		n   string
+		l   Literal
+		exp string // expected rational value, empty if an error is expected
+		err error  // expected error of IntegerValue, if any
+	}{
+		{"integer", Literal{NumberLiteralKind, "42", "42", EOS}, "42", nil},
+		{"underscores", Literal{NumberLiteralKind, "1_000_000", "1_000_000", EOS}, "1000000", nil},
+		{"scientific", Literal{NumberLiteralKind, "1e18", "1e18", EOS}, "1000000000000000000", nil},
+		{"fraction", Literal{NumberLiteralKind, "1.5", "1.5", EOS}, "3/2", ErrNotInteger},
+		{"leading period", Literal{NumberLiteralKind, ".5e10", ".5e10", EOS}, "5000000000", nil},
+		{"negative exponent", Literal{NumberLiteralKind, "25e-2", "25e-2", EOS}, "1/4", ErrNotInteger},
+		{"hex", Literal{NumberLiteralKind, "0xff_ff", "0xff_ff", EOS}, "65535", nil},
+		{"ether", Literal{NumberLiteralKind, "1.5", "1.5", SubEther}, "1500000000000000000", nil},
+		{"gwei", Literal{NumberLiteralKind, "3", "3", SubGwei}, "3000000000", nil},
+		{"days", Literal{NumberLiteralKind, "3", "3", SubDay}, "259200", nil},
+		{"max uint256", Literal{NumberLiteralKind, "0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff", "0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff", EOS},
+			"115792089237316195423570985008687907853269984665640564039457584007913129639935", nil},
+		{"overflow", Literal{NumberLiteralKind, "1e78", "1e78", EOS}, "1000000000000000000000000000000000000000000000000000000000000000000000000000000", ErrOverflow},
+		{"hex with unit", Literal{NumberLiteralKind, "0x10", "0x10", SubEther}, "", nil},
+		{"huge exponent", Literal{NumberLiteralKind, "1e1000000000", "1e1000000000", EOS}, "", nil},
+		{"not a number", Literal{StringLiteralKind, "abc", `"abc"`, EOS}, "", nil},
+	}
+
+	for _, tt := range tests {
+		r, err := tt.l.RationalValue()
+		if tt.exp == "" {
+			if err == nil {
+				t.Errorf("%s: expected error got %s", tt.n, r)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error %s", tt.n, err)
+			continue
+		}
+		if r.RatString() != tt.exp {
+			t.Errorf("%s: expected %s got %s", tt.n, tt.exp, r.RatString())
+		}
+
+		i, err := tt.l.IntegerValue()
+		if err != tt.err {
+			t.Errorf("%s: expected error '%v' got '%v'", tt.n, tt.err, err)
+		}
+		if err == nil && i.String() != tt.exp {
+			t.Errorf("%s: expected integer %s got %s", tt.n, tt.exp, i)
+		}
+	}
+}
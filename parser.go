@@ -1,6 +1,7 @@
 package solparse
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"io"
@@ -8,6 +9,11 @@ import (
 
 type Node interface{}
 
+// SourceUnit holds the top level definitions of a source file
+type SourceUnit struct {
+	Nodes []Node
+}
+
 type ContractDefinition struct {
 	BaseContracts  []ContractDefinition
 	SourceLocation string
@@ -21,12 +27,21 @@ type VariableDeclaration struct {
 	Type            TypeName
 	Identifier      string
 	Value           Expression
+	Visibility      string
 	IsStateVariable bool
 	IsIndexed       bool
 	IsDeclaredConst bool
 	Location        string
 }
 
+// Comment is a comment kept by a parser created with NewParserWithComments.
+// Text includes the comment markers. Trailing comments follow the previous
+// node on the same line
+type Comment struct {
+	Text     string
+	Trailing bool
+}
+
 // Assignment and Conditional should implement Expression
 type Expression interface{}
 
@@ -39,21 +54,52 @@ type IdentifierExpression struct {
 	Literal string
 }
 
-type AssignmentExpression struct {
-	Expression         Token
+type LiteralKind int
+
+const (
+	BoolLiteralKind LiteralKind = iota
+	NumberLiteralKind
+	StringLiteralKind
+	HexStringLiteralKind
+	UnicodeStringLiteralKind
+)
+
+// Literal is a constant in the source. Value is the text of booleans and
+// numbers as written and the decoded contents of string literals. Raw is the
+// literal as written, adjacent string literals like "a" "b" are joined by a
+// single space. SubDenomination is the unit of a number like 1 ether or
+// 2 days, or EOS if there is none
+type Literal struct {
+	Kind            LiteralKind
+	Value           string
+	Raw             string
+	SubDenomination Token
+}
+
+type Assignment struct {
+	LeftHandSide       Expression
 	AssignmentOperator Token
-	RightHandSide      string
+	RightHandSide      Expression
 }
 
 type ConditionalExpression struct {
-	TrueExpression  string
-	FalseExpression string
+	Condition       Expression
+	TrueExpression  Expression
+	FalseExpression Expression
+}
+
+// TupleExpression is a parenthesized, comma separated list of expressions
+// or an inline array [a, b, c]. Components contains nil for components
+// left out of a tuple, e.g. (a, , c)
+type TupleExpression struct {
+	Components    []Expression
+	IsInlineArray bool
 }
 
 type UnaryOperation struct {
 	Token         Token
 	SubExpression Expression
-	Something     bool
+	IsPrefix      bool
 }
 
 type BinaryOperation struct {
@@ -62,38 +108,201 @@ type BinaryOperation struct {
 	RightHandSide Expression
 }
 
+type MemberAccess struct {
+	Expression Expression
+	MemberName string
+}
+
+// IndexAccess represents base[index]. IndexExpression is nil for base[]
+// which is only meaningful as a type, e.g. abi.decode(data, (uint[]))
+type IndexAccess struct {
+	BaseExpression  Expression
+	IndexExpression Expression
+}
+
+// IndexRangeAccess represents a slice base[start:end], either bound is nil
+// when omitted
+type IndexRangeAccess struct {
+	BaseExpression  Expression
+	StartExpression Expression
+	EndExpression   Expression
+}
+
+// FunctionCall represents a call or type conversion. Names is only set for
+// calls with named arguments like f({a: 1, b: 2}) and matches Arguments
+type FunctionCall struct {
+	Expression Expression
+	Arguments  []Expression
+	Names      []string
+}
+
+// NewExpression is the callee in contract creations and dynamic array
+// allocations, e.g. new Token(...) or new uint[](n)
+type NewExpression struct {
+	TypeName TypeName
+}
+
+// FunctionCallOptions represents expression{value: v, gas: g}, Names and
+// Options line up
+type FunctionCallOptions struct {
+	Expression Expression
+	Options    []Expression
+	Names      []string
+}
+
 type TypeName interface{}
 
+// UserDefinedTypeName refers to a contract, struct or enum, possibly through
+// a path like Library.Struct
+type UserDefinedTypeName struct {
+	NamePath []string
+}
+
+// ArrayTypeName represents T[Length], Length is nil for dynamic arrays
+type ArrayTypeName struct {
+	BaseType TypeName
+	Length   Expression
+}
+
+// ElementaryTypeName is a builtin type. FirstSize and SecondSize are the
+// sizes of sized types, e.g. 8 for uint8 or 128 and 18 for fixed128x18
 type ElementaryTypeName struct {
 	Token
-	firstSize  int
-	secondSize int
+	FirstSize  int
+	SecondSize int
 }
 
 type Block struct {
 	Statements []Node
 }
 
+// UncheckedBlock is a block whose arithmetic is not checked for overflow
+type UncheckedBlock struct {
+	Statements []Node
+}
+
+// PlaceholderStatement is the _ in a modifier body where the body of the
+// modified function is inserted
+type PlaceholderStatement struct{}
+
+type ReturnStatement struct {
+	Expression Expression // nil for a bare return
+}
+
+// VariableDeclarationStatement declares one or more local variables.
+// Declarations contains nil for components left out of a tuple declaration
+type VariableDeclarationStatement struct {
+	Declarations []*VariableDeclaration
+	InitialValue Expression
+}
+
+type IfStatement struct {
+	Condition Expression
+	TrueBody  Node
+	FalseBody Node // nil if there is no else branch
+}
+
+type WhileStatement struct {
+	Condition Expression
+	Body      Node
+}
+
+type DoWhileStatement struct {
+	Condition Expression
+	Body      Node
+}
+
+// ForStatement represents a for loop, each of the parts inside the
+// parenthesis is nil when omitted
+type ForStatement struct {
+	InitializationExpression Node
+	Condition                Expression
+	LoopExpression           Expression
+	Body                     Node
+}
+
+// TryStatement represents try with its catch clauses. The first clause
+// holds the returns parameters and the block run on success
+type TryStatement struct {
+	Expression Expression
+	Clauses    []TryCatchClause
+}
+
+// TryCatchClause is the success clause or a catch clause of a try
+// statement. ErrorName is Error, Panic or empty for a catch all clause
+type TryCatchClause struct {
+	ErrorName  string
+	Parameters ParameterList
+	Block      Block
+}
+
+// FunctionKind distinguishes ordinary functions from the special entry
+// points of a contract
+type FunctionKind int
+
+const (
+	OrdinaryFunction FunctionKind = iota
+	ConstructorFunction
+	ReceiveFunction
+	FallbackFunction
+)
+
 type FunctionDefinition struct {
 	Name             string
+	Kind             FunctionKind
+	IsLegacyFallback bool // fallback declared as an unnamed function()
 	Visibility       string
 	IsConstructor    bool
 	DocString        string
 	Paramaters       ParameterList
 	IsDeclaredConst  bool
-	Modifiers        []string
+	Modifiers        []ModifierInvocation
 	ReturnParameters ParameterList
 	IsPayable        bool
+	IsView           bool
+	IsPure           bool
 	Block            Node
 }
 
+type ModifierDefinition struct {
+	Name       string
+	Paramaters ParameterList
+	Block      Node // nil if the modifier has no implementation
+}
+
+type ModifierInvocation struct {
+	Name      string
+	Arguments []Expression
+}
+
 type ParameterList struct {
 	Paramaters []VariableDeclaration
 }
 
+// varDeclOptions controls which parts of a variable declaration are
+// accepted in the current context
+type varDeclOptions struct {
+	allowVar               bool
+	isStateVariable        bool
+	allowIndexed           bool
+	allowEmptyName         bool
+	allowInitialValue      bool
+	allowLocationSpecifier bool
+}
+
 // Parser represents a parser.
 type Parser struct {
 	s *Scanner
+
+	insideModifier  bool
+	insideUnchecked bool
+
+	// open syntax tree nodes, nil unless building a syntax tree
+	syntaxStack []*SyntaxNode
+
+	keepComments  bool
+	lastEnd       int    // end of the last consumed token
+	innerComments []Span // comments inside of statements and declarations
 }
 
 // NewParser returns a new instance of Parser.
@@ -101,32 +310,71 @@ func NewParser(r io.Reader) *Parser {
 	return &Parser{s: NewScanner(r)}
 }
 
+// NewParserWithComments returns a parser that keeps comments as Comment
+// nodes in source units, contract bodies and blocks. Comments inside of
+// statements or declarations are not nodes, Format places them next to the
+// tokens around them
+func NewParserWithComments(r io.Reader) *Parser {
+	return &Parser{s: NewTriviaScanner(r), keepComments: true}
+}
+
 // Parse the buffer
-func (p *Parser) Parse() (cd *ContractDefinition, err error) {
-	// Must be import, pragma, contract, library
-	tok := p.currentToken()
-	for tok != EOS {
-		switch tok {
-		case Pragma:
-			return cd, errors.New("pragma not yet implemented")
-		case Import:
-			return cd, errors.New("import not yet implemented")
-		case Contract, Library:
-			cd, err = p.parseContractDefination(false)
+func (p *Parser) Parse() (su *SourceUnit, err error) {
+	su = &SourceUnit{}
+	// Must be import, pragma, contract, library, free function or constant
+	for tok := p.currentToken(); tok != EOS; tok = p.currentToken() {
+		su.Nodes = append(su.Nodes, p.takeComments()...)
+		switch {
+		case tok == Pragma:
+			return su, errors.New("pragma not yet implemented")
+		case tok == Import:
+			return su, errors.New("import not yet implemented")
+		case tok == Contract || tok == Library:
+			cd, err := p.parseContractDefination(tok == Library)
 			if err != nil {
-				return cd, err
+				return su, err
+			}
+			su.Nodes = append(su.Nodes, *cd)
+		case tok == Function:
+			fd, err := p.parseFunctionDefinition("")
+			if err != nil {
+				return su, err
+			}
+			if fd.Kind != OrdinaryFunction {
+				return su, errors.New("Free functions must have a name")
+			}
+			if fd.Visibility != "" {
+				return su, errors.New("Free functions cannot have visibility")
+			}
+			if fd.Block == nil {
+				return su, errors.New("Free functions must be implemented")
+			}
+			su.Nodes = append(su.Nodes, fd)
+		case tok == Identifier || tok == Mapping || IsElementaryTypeName(tok):
+			vd, err := p.parseVariableDeclaration(varDeclOptions{allowInitialValue: true}, nil)
+			if err != nil {
+				return su, err
+			}
+			if !vd.IsDeclaredConst {
+				return su, errors.New("Only constant variables are allowed at file level")
+			}
+			su.Nodes = append(su.Nodes, vd)
+			err = p.expectToken(Semicolon)
+			if err != nil {
+				return su, err
 			}
 		default:
-			err = errors.New("Expected import directive or contract definition")
+			return su, errors.New("Expected import directive, contract, function or constant definition")
 		}
-		tok = p.next()
 	}
+	su.Nodes = append(su.Nodes, p.takeComments()...)
 
 	return
 }
 
 // Parses contract or library definition
 func (p *Parser) parseContractDefination(isLib bool) (cd *ContractDefinition, err error) {
+	defer p.markNode(&cd)()
 	cd = &ContractDefinition{}
 	if isLib {
 		err = p.expectToken(Library)
@@ -155,12 +403,13 @@ func (p *Parser) parseContractDefination(isLib bool) (cd *ContractDefinition, er
 
 outer:
 	for {
+		cd.SubNodes = append(cd.SubNodes, p.takeComments()...)
 		tok := p.currentToken()
 		switch {
 		case tok == RBrace:
 			break outer
-		case tok == Function:
-			fd, err := p.parseFunctionDefinition()
+		case tok == Function || tok == Constructor || p.atReceiveOrFallback():
+			fd, err := p.parseFunctionDefinition(cd.Name)
 			if err != nil {
 				return cd, err
 			}
@@ -169,18 +418,23 @@ outer:
 			return cd, errors.New("struct not yet implemented")
 		case tok == Enum:
 			return cd, errors.New("enum not yet implemented")
-		case tok == Identifier || tok == Mapping || isElementaryTypeName(tok):
-			vd, err := p.parseVariableDeclaration()
+		case tok == Identifier || tok == Mapping || IsElementaryTypeName(tok):
+			vd, err := p.parseVariableDeclaration(varDeclOptions{isStateVariable: true, allowInitialValue: true}, nil)
 			if err != nil {
 				return cd, err
 			}
+			vd.IsStateVariable = true
 			cd.SubNodes = append(cd.SubNodes, vd)
 			err = p.expectToken(Semicolon)
 			if err != nil {
 				return cd, err
 			}
 		case tok == Modifier:
-			return cd, errors.New("modifier not yet implemented")
+			md, err := p.parseModifierDefinition()
+			if err != nil {
+				return cd, err
+			}
+			cd.SubNodes = append(cd.SubNodes, md)
 		case tok == Event:
 			return cd, errors.New("event not yet implemented")
 		case tok == Using:
@@ -193,52 +447,99 @@ outer:
 	return
 }
 
-func (p *Parser) parseFunctionDefinition() (f FunctionDefinition, err error) {
-	err = p.expectToken(Function)
-	if err != nil {
-		return f, err
+// atReceiveOrFallback reports whether the current token starts a receive or
+// fallback function. Neither name is reserved, so they are only treated as
+// such when followed by a parameter list
+func (p *Parser) atReceiveOrFallback() bool {
+	if p.currentToken() != Identifier || p.s.peekNextToken() != LParen {
+		return false
 	}
-	tok := p.currentToken()
-	if tok == LBrace {
-		// anonymous function
-	} else {
-		f.Name, err = p.expectIdentifierToken()
+	lit := p.currentLiteral()
+	return lit == "receive" || lit == "fallback"
+}
+
+func (p *Parser) parseFunctionDefinition(contractName string) (f FunctionDefinition, err error) {
+	defer p.markNode(&f)()
+	switch p.currentToken() {
+	case Constructor:
+		f.Kind = ConstructorFunction
+		p.next()
+	case Identifier:
+		if p.currentLiteral() == "receive" {
+			f.Kind = ReceiveFunction
+		} else {
+			f.Kind = FallbackFunction
+		}
+		p.next()
+	default:
+		err = p.expectToken(Function)
 		if err != nil {
 			return f, err
 		}
+		if p.currentToken() == LParen {
+			// unnamed legacy fallback function
+			f.Kind = FallbackFunction
+			f.IsLegacyFallback = true
+		} else {
+			f.Name, err = p.expectIdentifierToken()
+			if err != nil {
+				return f, err
+			}
+			if f.Name == contractName {
+				// legacy constructor named after its contract
+				f.Kind = ConstructorFunction
+			}
+		}
 	}
+	f.IsConstructor = f.Kind == ConstructorFunction
 
-	f.Paramaters, err = p.parseParameterList() // pass in options
+	paramOptions := varDeclOptions{allowEmptyName: true, allowLocationSpecifier: true}
+	f.Paramaters, err = p.parseParameterList(paramOptions)
+	if err != nil {
+		return f, err
+	}
 
 	// Parse function modifiers like constant
 	for {
 		tok := p.currentToken()
-		if tok == Const {
-			f.IsDeclaredConst = true
-			p.next()
-		} else if tok == Payable {
-			f.IsPayable = true
+		if IsStateMutabilitySpecifier(tok) {
+			if f.IsDeclaredConst || f.IsPayable || f.IsView || f.IsPure {
+				return f, errors.New("State mutability already specified")
+			}
+			switch tok {
+			case Const:
+				f.IsDeclaredConst = true
+			case Payable:
+				f.IsPayable = true
+			case View:
+				f.IsView = true
+			case Pure:
+				f.IsPure = true
+			}
 			p.next()
-		} else if isVisibilitySpecifier(tok) {
+		} else if IsVisibilitySpecifier(tok) {
+			if f.Visibility != "" {
+				return f, errors.New("Visibility already specified")
+			}
 			f.Visibility, err = p.parseVisibilitySpecifier()
 			if err != nil {
 				return f, err
 			}
 		} else if tok == Identifier {
-			_, err := p.parseModifierInvocation()
+			m, err := p.parseModifierInvocation()
 			if err != nil {
 				return f, err
 			}
-			// Add modifer to function def
+			f.Modifiers = append(f.Modifiers, m)
 		} else {
 			break
 		}
 	}
 
-	tok = p.currentToken()
+	tok := p.currentToken()
 	if tok == Returns {
 		p.next()
-		f.ReturnParameters, err = p.parseParameterList() // allowEmptyParamaterList = false
+		f.ReturnParameters, err = p.parseParameterList(paramOptions) // allowEmptyParamaterList = false
 		if err != nil {
 			return f, err
 		}
@@ -254,29 +555,85 @@ func (p *Parser) parseFunctionDefinition() (f FunctionDefinition, err error) {
 		p.next() // consume ;
 	}
 
-	// If f.Name == _contractName { f.Constructor = true }
+	return
+}
+
+func (p *Parser) parseModifierDefinition() (m ModifierDefinition, err error) {
+	defer p.markNode(&m)()
+	err = p.expectToken(Modifier)
+	if err != nil {
+		return
+	}
+	m.Name, err = p.expectIdentifierToken()
+	if err != nil {
+		return
+	}
+	if p.currentToken() == LParen {
+		m.Paramaters, err = p.parseParameterList(varDeclOptions{allowLocationSpecifier: true})
+		if err != nil {
+			return
+		}
+	}
+
+	if p.currentToken() == Semicolon {
+		p.next()
+		return
+	}
+	p.insideModifier = true
+	m.Block, err = p.parseBlock()
+	p.insideModifier = false
 	return
 }
 
 func (p *Parser) parseBlock() (b Block, err error) {
+	defer p.markNode(&b)()
 	err = p.expectToken(LBrace)
 	if err != nil {
 		return
 	}
 
+	b.Statements = append(b.Statements, p.takeComments()...)
 	for p.currentToken() != RBrace {
-		stmt, err := p.parseStatement()
+		var stmt Node
+		// unchecked blocks are only allowed directly inside other blocks
+		if p.currentToken() == Unchecked {
+			stmt, err = p.parseUncheckedBlock()
+		} else {
+			stmt, err = p.parseStatement()
+		}
 		if err != nil {
 			return b, err
 		}
 		b.Statements = append(b.Statements, stmt)
+		b.Statements = append(b.Statements, p.takeComments()...)
 	}
 
 	err = p.expectToken(RBrace)
 	return b, err
 }
 
+func (p *Parser) parseUncheckedBlock() (u UncheckedBlock, err error) {
+	defer p.markNode(&u)()
+	err = p.expectToken(Unchecked)
+	if err != nil {
+		return
+	}
+	if p.insideUnchecked {
+		return u, errors.New("Unchecked blocks cannot be nested")
+	}
+	if p.insideModifier {
+		return u, errors.New("Unchecked blocks cannot be used inside of modifiers")
+	}
+
+	p.insideUnchecked = true
+	b, err := p.parseBlock()
+	p.insideUnchecked = false
+	u.Statements = b.Statements
+	return
+}
+
 func (p *Parser) parseStatement() (e Expression, err error) {
+	defer p.markNode(&e)()
 	s := Statement{}
 	// check for comment
 	tok := p.currentToken()
@@ -287,36 +644,43 @@ func (p *Parser) parseStatement() (e Expression, err error) {
 		return p.parseWhileStatement()
 	case For:
 		return p.parseForStatement()
+	case Do:
+		return p.parseDoWhileStatement()
+	case Try:
+		return p.parseTryStatement()
 	case LBrace:
 		return p.parseBlock()
 	case Continue, Break, Throw:
 		s.Token = tok
 		_ = p.next()
 	case Return:
-		// Build expression
-		e := Statement{}
+		r := ReturnStatement{}
 		if p.next() != Semicolon {
-			e, err := p.parseExpression()
+			r.Expression, err = p.parseExpression()
 			if err != nil {
-				return e, err
+				return r, err
 			}
-		} else {
-			s.Expression = e
 		}
+		err = p.expectToken(Semicolon)
+		return r, err
 	case Assemby:
 		return p.parseInlineAssembly()
+	case Unchecked:
+		return e, errors.New("'unchecked' blocks can only be used inside regular blocks")
 	case Identifier:
-		if p.currentLiteral() == "_" { // and inside modifer {
-			return e, errors.New("'_' not yet implemented")
+		if p.insideModifier && p.currentLiteral() == "_" {
+			p.next()
+			err = p.expectToken(Semicolon)
+			return PlaceholderStatement{}, err
 		}
-		// if inside of a function modifier and current Literal = '_'
-		//  -> statement = PlaceHolderStatement
 		fallthrough
 	default:
-		s.Expression, err = p.parseSimpleStatement()
+		e, err = p.parseSimpleStatement()
 		if err != nil {
-			return s, err
+			return e, err
 		}
+		err = p.expectToken(Semicolon)
+		return e, err
 	}
 
 	err = p.expectToken(Semicolon)
@@ -327,14 +691,15 @@ func (p *Parser) parseInheritanceSpecifier() (cds []ContractDefinition, err erro
 	return cds, errors.New("Inheritance specifiers not yet implemented")
 }
 
-func (p *Parser) parseParameterList() (pl ParameterList, err error) {
+func (p *Parser) parseParameterList(options varDeclOptions) (pl ParameterList, err error) {
+	defer p.markNode(&pl)()
 	err = p.expectToken(LParen)
 	if err != nil {
 		return
 	}
 
 	if p.currentToken() != RParen { // || !_allowEmpty
-		vd, err := p.parseVariableDeclaration()
+		vd, err := p.parseVariableDeclaration(options, nil)
 		if err != nil {
 			return pl, err
 		}
@@ -346,7 +711,7 @@ func (p *Parser) parseParameterList() (pl ParameterList, err error) {
 			if err != nil {
 				return pl, err
 			}
-			vd, err := p.parseVariableDeclaration()
+			vd, err := p.parseVariableDeclaration(options, nil)
 			if err != nil {
 				return pl, err
 			}
@@ -358,59 +723,78 @@ func (p *Parser) parseParameterList() (pl ParameterList, err error) {
 	return
 }
 
-func (p *Parser) parseVariableDeclaration() (v VariableDeclaration, err error) {
-	// if lookAheadArrayType
-	//
-	// else {
-	v.Type, err = p.parseTypeName() // option: allowVar
-	if err != nil {
-		return v, err
+// parseVariableDeclaration parses a declaration, lookAheadType is the already
+// parsed type if the statement needed to be disambiguated first
+func (p *Parser) parseVariableDeclaration(options varDeclOptions, lookAheadType TypeName) (v VariableDeclaration, err error) {
+	defer p.markNode(&v)()
+	if lookAheadType != nil {
+		v.Type = lookAheadType
+	} else {
+		v.Type, err = p.parseTypeName(options.allowVar)
+		if err != nil {
+			return v, err
+		}
 	}
 
-	// next check const, indexed, storage, memory, etc
-	//for {
-	//tok, lit := p.currentToken
-
-	// if isVariableVisibilitySpecifier(tok) -> set v.Visibility
-	// else
-	//   if INDEXED -> set Indexed
-	//   else if CONST -> set Const
-	//   else if isLocationSpecifier -> set location
-	//   else break
-	//break
-	//}
-
-	// if allowEmptyName && currentToken != IDENT { identifier = "" }
+	for {
+		tok := p.currentToken()
+		if options.isStateVariable && IsVisibilitySpecifier(tok) && tok != External {
+			if v.Visibility != "" {
+				return v, errors.New("Visibility already specified")
+			}
+			v.Visibility = tok.String()
+		} else if options.allowIndexed && tok == Indexed {
+			v.IsIndexed = true
+		} else if tok == Const {
+			v.IsDeclaredConst = true
+		} else if options.allowLocationSpecifier && IsLocationSpecifier(tok) {
+			if v.Location != "" {
+				return v, errors.New("Location already specified")
+			}
+			v.Location = tok.String()
+		} else {
+			break
+		}
+		p.next()
+	}
 
-	v.Identifier, err = p.expectIdentifierToken()
+	if options.allowEmptyName && p.currentToken() != Identifier {
+		v.Identifier = ""
+	} else {
+		v.Identifier, err = p.expectIdentifierToken()
+		if err != nil {
+			return v, err
+		}
+	}
 
-	// if allowInitialValue -> Check for ASSIGN, parseExpression
-	if p.currentToken() == Assign {
+	if options.allowInitialValue && p.currentToken() == Assign {
 		p.next()
 		v.Value, err = p.parseExpression()
 		if err != nil {
 			return v, err
 		}
-
 	}
 	return
 }
 
-func (p *Parser) parseTypeName() (t TypeName, err error) {
+func (p *Parser) parseTypeName(allowVar bool) (t TypeName, err error) {
+	defer p.markNode(&t)()
 	tok := p.currentToken()
-	if isElementaryTypeName(tok) {
+	switch {
+	case IsElementaryTypeName(tok):
 		firstSize, secondSize := p.currentTokenInfo()
-		t := ElementaryTypeName{tok, firstSize, secondSize}
+		t = ElementaryTypeName{tok, firstSize, secondSize}
 		p.next()
-		return t, nil
-	}
-
-	switch tok {
-	case Var:
-		//   return error if var not allowed (by option)
-	case Mapping:
+	case tok == Var:
+		if !allowVar {
+			return t, errors.New("Expected explicit type name")
+		}
+		// the type is inferred from the assigned value
+		p.next()
+		return
+	case tok == Mapping:
 		t, err = p.parseMapping()
-	case Identifier:
+	case tok == Identifier:
 		t, err = p.parseUserDefinedTypeName()
 	default:
 		return t, errors.New("Expected type name")
@@ -420,133 +804,665 @@ func (p *Parser) parseTypeName() (t TypeName, err error) {
 	}
 
 	// Handle [...] postfix for arrays
+	for p.currentToken() == LBrack {
+		p.next()
+		var length Expression
+		if p.currentToken() != RBrack {
+			length, err = p.parseExpression()
+			if err != nil {
+				return t, err
+			}
+		}
+		err = p.expectToken(RBrack)
+		if err != nil {
+			return t, err
+		}
+		t = ArrayTypeName{BaseType: t, Length: length}
+	}
 	return
 }
 
 func (p *Parser) parseVisibilitySpecifier() (v string, err error) {
-	return v, errors.New("visibilty specifiers not yet implemented")
-}
-
-func (p *Parser) parseModifierInvocation() (m string, err error) {
-	return m, errors.New("function modifiers not yet implemented")
-}
-
-func (p *Parser) parseMapping() (t TypeName, err error) {
-	return t, errors.New("mapping not yet implemented")
-}
-
-func (p *Parser) parseUserDefinedTypeName() (t TypeName, err error) {
-	return t, errors.New("user defined types not yet implemented")
-}
-
-func (p *Parser) parseIfStatement() (s Statement, err error) {
-	return s, errors.New("if statement not yet implemented")
-}
-
-func (p *Parser) parseWhileStatement() (s Statement, err error) {
-	return s, errors.New("while statement not yet implemented")
-}
-
-func (p *Parser) parseForStatement() (s Statement, err error) {
-	return s, errors.New("for statement not yet implemented")
+	tok := p.currentToken()
+	if !IsVisibilitySpecifier(tok) {
+		return v, fmt.Errorf("Expected visibility specifier, got '%s'", tok)
+	}
+	p.next()
+	return tok.String(), nil
 }
 
-func (p *Parser) parseExpression() (e Expression, err error) {
-	e, err = p.parseBinaryExpression(4) // + lookaheadDataStructure
+func (p *Parser) parseModifierInvocation() (m ModifierInvocation, err error) {
+	defer p.markNode(&m)()
+	m.Name, err = p.expectIdentifierToken()
 	if err != nil {
-		return e, err
+		return m, err
 	}
-
-	if isAssignmentOp(p.currentToken()) {
-		return e, errors.New("assignment expression not yet implemented")
-		//assignmentOp := p.expectAssignmentOperator()
-	} else if p.currentToken() == Conditional {
-		return e, errors.New("conditional not yet implemented")
-		//p.next()
+	if p.currentToken() == LParen {
+		p.next()
+		m.Arguments, err = p.parseFunctionCallListArguments()
+		if err != nil {
+			return m, err
+		}
+		err = p.expectToken(RParen)
 	}
-
 	return
 }
 
-func (p *Parser) parseInlineAssembly() (s Statement, err error) {
-	return s, errors.New("inline assembly not yet implemented")
-}
-
-func (p *Parser) parseSimpleStatement() (e Expression, err error) {
-	switch p.peekStatementType() {
-	case VariableDeclarationStatement:
-		return p.parseVariableDeclarationStatement()
-	case ExpressionStatement:
-		return p.parseExpressionStatement()
+// parseFunctionCallArguments parses either positional arguments or named
+// arguments of the form {a: 1, b: 2}
+func (p *Parser) parseFunctionCallArguments() (args []Expression, names []string, err error) {
+	if p.currentToken() != LBrace {
+		args, err = p.parseFunctionCallListArguments()
+		return
 	}
-
-	// TODO: Deal with [] and . variable access stuff
-
-	return
+	return p.parseNamedArguments()
 }
 
-func (p *Parser) parseVariableDeclarationStatement() (s Statement, err error) {
-	return s, errors.New("implement variable decleartion stmt")
-}
-
-func (p *Parser) parseExpressionStatement() (s Expression, err error) {
-	return p.parseExpression()
-}
-
-func (p *Parser) parseBinaryExpression(minPrecedence int) (e Expression, err error) {
-	e, err = p.parseUnaryExpression()
+// parseNamedArguments parses a braced list of name: value pairs as used by
+// named call arguments and call options
+func (p *Parser) parseNamedArguments() (args []Expression, names []string, err error) {
+	err = p.expectToken(LBrace)
 	if err != nil {
-		return e, err
+		return
 	}
-
-	// TODO: do we need tokenPrecence() or is this info stored with Token in Scanner
-	precedence := tokenPrecedence(p.currentToken())
-	for ; precedence >= minPrecedence; precedence-- {
-		for tokenPrecedence(p.currentToken()) == precedence {
-			op := p.currentToken()
-			p.next()
-			right, err := p.parseBinaryExpression(precedence + 1)
+	first := true
+	for p.currentToken() != RBrace {
+		if !first {
+			err = p.expectToken(Comma)
 			if err != nil {
-				return e, err
+				return
 			}
-			e = BinaryOperation{e, op, right}
 		}
+		name, err := p.expectIdentifierToken()
+		if err != nil {
+			return args, names, err
+		}
+		err = p.expectToken(Colon)
+		if err != nil {
+			return args, names, err
+		}
+		e, err := p.parseExpression()
+		if err != nil {
+			return args, names, err
+		}
+		names = append(names, name)
+		args = append(args, e)
+		if p.currentToken() == Comma && p.s.peekNextToken() == RBrace {
+			return args, names, errors.New("Unexpected trailing comma")
+		}
+		first = false
 	}
+	err = p.expectToken(RBrace)
 	return
 }
 
-func (p *Parser) parseUnaryExpression() (e Expression, err error) {
-	u := UnaryOperation{}
-	u.Token = p.currentToken()
-
-	if isUnaryOp(u.Token) || isCountOp(u.Token) {
-		// prefix expression
-		p.next()
-		u.SubExpression, err = p.parseUnaryExpression()
-		return u, err
-	} else {
-		u.SubExpression, err = p.parseLeftHandSideExpression()
+// parseFunctionCallListArguments parses a comma separated list of
+// expressions up to (but not including) the closing parenthesis
+func (p *Parser) parseFunctionCallListArguments() (args []Expression, err error) {
+	if p.currentToken() == RParen {
+		return
+	}
+	for {
+		e, err := p.parseExpression()
 		if err != nil {
-			return u, err
+			return args, err
 		}
-		tok := p.currentToken()
-		if !isCountOp(tok) {
-			return u.SubExpression, nil
+		args = append(args, e)
+		if p.currentToken() != Comma {
+			return args, nil
 		}
 		p.next()
 	}
-	return
 }
 
-func (p *Parser) parseLeftHandSideExpression() (e Expression, err error) {
-	// if we were passed the lookAheadDataType, e = lookAheadDataType
-	// } else {
-	tok := p.currentToken()
-	if tok == New {
-		return e, errors.New("new not yet implemented")
-		// contract name = p.parseTypeName(false)
-		// e = contract
-	} else {
+func (p *Parser) parseMapping() (t TypeName, err error) {
+	return t, errors.New("mapping not yet implemented")
+}
+
+func (p *Parser) parseUserDefinedTypeName() (t UserDefinedTypeName, err error) {
+	name, err := p.expectIdentifierToken()
+	if err != nil {
+		return t, err
+	}
+	t.NamePath = append(t.NamePath, name)
+	for p.currentToken() == Period {
+		p.next()
+		name, err = p.expectIdentifierToken()
+		if err != nil {
+			return t, err
+		}
+		t.NamePath = append(t.NamePath, name)
+	}
+	return
+}
+
+func (p *Parser) parseIfStatement() (s IfStatement, err error) {
+	err = p.expectToken(If)
+	if err != nil {
+		return
+	}
+	s.Condition, err = p.parseParenthesizedCondition()
+	if err != nil {
+		return
+	}
+	s.TrueBody, err = p.parseStatement()
+	if err != nil {
+		return
+	}
+	// an else always belongs to the innermost if
+	if p.currentToken() == Else {
+		p.next()
+		s.FalseBody, err = p.parseStatement()
+	}
+	return
+}
+
+func (p *Parser) parseWhileStatement() (s WhileStatement, err error) {
+	err = p.expectToken(While)
+	if err != nil {
+		return
+	}
+	s.Condition, err = p.parseParenthesizedCondition()
+	if err != nil {
+		return
+	}
+	s.Body, err = p.parseStatement()
+	return
+}
+
+func (p *Parser) parseDoWhileStatement() (s DoWhileStatement, err error) {
+	err = p.expectToken(Do)
+	if err != nil {
+		return
+	}
+	s.Body, err = p.parseStatement()
+	if err != nil {
+		return
+	}
+	err = p.expectToken(While)
+	if err != nil {
+		return
+	}
+	s.Condition, err = p.parseParenthesizedCondition()
+	if err != nil {
+		return
+	}
+	err = p.expectToken(Semicolon)
+	return
+}
+
+func (p *Parser) parseForStatement() (s ForStatement, err error) {
+	err = p.expectToken(For)
+	if err != nil {
+		return
+	}
+	err = p.expectToken(LParen)
+	if err != nil {
+		return
+	}
+
+	if p.currentToken() != Semicolon {
+		s.InitializationExpression, err = p.parseSimpleStatement()
+		if err != nil {
+			return
+		}
+	}
+	err = p.expectToken(Semicolon)
+	if err != nil {
+		return
+	}
+
+	if p.currentToken() != Semicolon {
+		s.Condition, err = p.parseExpression()
+		if err != nil {
+			return
+		}
+	}
+	err = p.expectToken(Semicolon)
+	if err != nil {
+		return
+	}
+
+	if p.currentToken() != RParen {
+		s.LoopExpression, err = p.parseExpression()
+		if err != nil {
+			return
+		}
+	}
+	err = p.expectToken(RParen)
+	if err != nil {
+		return
+	}
+
+	s.Body, err = p.parseStatement()
+	return
+}
+
+// parseTryStatement parses try with its success clause and at least one
+// catch clause
+func (p *Parser) parseTryStatement() (s TryStatement, err error) {
+	err = p.expectToken(Try)
+	if err != nil {
+		return
+	}
+	s.Expression, err = p.parseExpression()
+	if err != nil {
+		return
+	}
+
+	paramOptions := varDeclOptions{allowEmptyName: true, allowLocationSpecifier: true}
+	success := TryCatchClause{}
+	if p.currentToken() == Returns {
+		p.next()
+		success.Parameters, err = p.parseParameterList(paramOptions)
+		if err != nil {
+			return
+		}
+	}
+	success.Block, err = p.parseBlock()
+	if err != nil {
+		return
+	}
+	s.Clauses = append(s.Clauses, success)
+
+	if p.currentToken() != Catch {
+		return s, fmt.Errorf("Expected 'catch', got '%s'", p.currentToken())
+	}
+	for p.currentToken() == Catch {
+		p.next()
+		c := TryCatchClause{}
+		if p.currentToken() == Identifier {
+			c.ErrorName = p.getLiteralAndAdvance()
+		}
+		if c.ErrorName != "" || p.currentToken() == LParen {
+			c.Parameters, err = p.parseParameterList(paramOptions)
+			if err != nil {
+				return
+			}
+		}
+		c.Block, err = p.parseBlock()
+		if err != nil {
+			return
+		}
+		s.Clauses = append(s.Clauses, c)
+	}
+	return
+}
+
+// parseParenthesizedCondition parses the '(' condition ')' of if and while
+// statements
+func (p *Parser) parseParenthesizedCondition() (e Expression, err error) {
+	err = p.expectToken(LParen)
+	if err != nil {
+		return
+	}
+	e, err = p.parseExpression()
+	if err != nil {
+		return
+	}
+	err = p.expectToken(RParen)
+	return
+}
+
+func (p *Parser) parseExpression() (e Expression, err error) {
+	defer p.markNode(&e)()
+	return p.parseExpressionWithLookAhead(nil)
+}
+
+// parseExpressionWithLookAhead parses an expression whose leading operand
+// has already been parsed while disambiguating a statement
+func (p *Parser) parseExpressionWithLookAhead(partial Expression) (e Expression, err error) {
+	e, err = p.parseBinaryExpression(4, partial)
+	if err != nil {
+		return e, err
+	}
+
+	if IsAssignmentOp(p.currentToken()) {
+		a := Assignment{LeftHandSide: e, AssignmentOperator: p.currentToken()}
+		p.next()
+		// assignment is right associative
+		a.RightHandSide, err = p.parseExpression()
+		return a, err
+	} else if p.currentToken() == Conditional {
+		c := ConditionalExpression{Condition: e}
+		p.next()
+		c.TrueExpression, err = p.parseExpression()
+		if err != nil {
+			return c, err
+		}
+		err = p.expectToken(Colon)
+		if err != nil {
+			return c, err
+		}
+		c.FalseExpression, err = p.parseExpression()
+		return c, err
+	}
+
+	return
+}
+
+func (p *Parser) parseSimpleStatement() (n Node, err error) {
+	if p.currentToken() == LParen {
+		return p.parseTupleStatement()
+	}
+
+	stype, path, err := p.tryParseIndexAccessedPath()
+	if err != nil {
+		return n, err
+	}
+	switch stype {
+	case VariableDeclarationStatementType:
+		var lookAheadType TypeName
+		if path != nil {
+			lookAheadType, err = p.typeNameFromIndexAccessStructure(path)
+			if err != nil {
+				return n, err
+			}
+		}
+		return p.parseVariableDeclarationStatement(lookAheadType)
+	default:
+		if path == nil {
+			return p.parseExpressionStatement()
+		}
+		e, err := p.parseExpressionWithLookAhead(p.expressionFromIndexAccessStructure(path))
+		return Statement{Expression: e}, err
+	}
+}
+
+// parseTupleStatement parses a simple statement starting with '(', which is
+// either a tuple variable declaration like (uint a, , bool c) = f() or an
+// expression statement
+func (p *Parser) parseTupleStatement() (n Node, err error) {
+	err = p.expectToken(LParen)
+	if err != nil {
+		return
+	}
+	var empty []Expression
+	for p.currentToken() == Comma {
+		p.next()
+		empty = append(empty, nil)
+	}
+
+	stype, path, err := p.tryParseIndexAccessedPath()
+	if err != nil {
+		return n, err
+	}
+
+	if stype != VariableDeclarationStatementType {
+		var partial Expression
+		if path != nil {
+			partial = p.expressionFromIndexAccessStructure(path)
+		}
+		t, err := p.parseTupleComponents(empty, partial)
+		if err != nil {
+			return t, err
+		}
+		e, err := p.parseExpressionWithLookAhead(t)
+		return Statement{Expression: e}, err
+	}
+
+	var lookAheadType TypeName
+	if path != nil {
+		lookAheadType, err = p.typeNameFromIndexAccessStructure(path)
+		if err != nil {
+			return n, err
+		}
+	}
+	s := VariableDeclarationStatement{Declarations: make([]*VariableDeclaration, len(empty))}
+	for {
+		var vd *VariableDeclaration
+		if tok := p.currentToken(); lookAheadType != nil || (tok != Comma && tok != RParen) {
+			d, err := p.parseVariableDeclaration(varDeclOptions{allowLocationSpecifier: true}, lookAheadType)
+			if err != nil {
+				return s, err
+			}
+			lookAheadType = nil
+			vd = &d
+		}
+		s.Declarations = append(s.Declarations, vd)
+		if p.currentToken() == RParen {
+			break
+		}
+		err = p.expectToken(Comma)
+		if err != nil {
+			return s, err
+		}
+	}
+	err = p.expectToken(RParen)
+	if err != nil {
+		return s, err
+	}
+	err = p.expectToken(Assign)
+	if err != nil {
+		return s, err
+	}
+	s.InitialValue, err = p.parseExpression()
+	return s, err
+}
+
+func (p *Parser) parseVariableDeclarationStatement(lookAheadType TypeName) (s VariableDeclarationStatement, err error) {
+	if lookAheadType == nil && p.currentToken() == Var && p.s.peekNextToken() == LParen {
+		// legacy var (a, , c) = ...
+		p.next()
+		p.next()
+		if p.currentToken() != RParen {
+			for {
+				var vd *VariableDeclaration
+				if tok := p.currentToken(); tok != Comma && tok != RParen {
+					name, err := p.expectIdentifierToken()
+					if err != nil {
+						return s, err
+					}
+					vd = &VariableDeclaration{Identifier: name}
+				}
+				s.Declarations = append(s.Declarations, vd)
+				if p.currentToken() == RParen {
+					break
+				}
+				err = p.expectToken(Comma)
+				if err != nil {
+					return s, err
+				}
+			}
+		}
+		err = p.expectToken(RParen)
+		if err != nil {
+			return s, err
+		}
+	} else {
+		vd, err := p.parseVariableDeclaration(varDeclOptions{allowVar: true, allowLocationSpecifier: true}, lookAheadType)
+		if err != nil {
+			return s, err
+		}
+		s.Declarations = append(s.Declarations, &vd)
+	}
+
+	if p.currentToken() == Assign {
+		p.next()
+		s.InitialValue, err = p.parseExpression()
+	}
+	return
+}
+
+// indexAccessedPath holds the leading 'Identifier(.Identifier)*([...])*' or
+// 'ElementaryTypeName([...])*' of a statement, parsed before it is known
+// whether it names a type or starts an expression
+type indexAccessedPath struct {
+	path    []Expression
+	indices []pathIndex
+}
+
+type pathIndex struct {
+	start   Expression
+	end     Expression
+	isRange bool
+}
+
+// tryParseIndexAccessedPath decides whether the statement at the current
+// token is a variable declaration or an expression. If that needs more than
+// one token of lookahead the ambiguous prefix is parsed and returned
+func (p *Parser) tryParseIndexAccessedPath() (StatementType, *indexAccessedPath, error) {
+	stype := p.peekStatementType()
+	if stype != IndexAccessStructureType {
+		return stype, nil, nil
+	}
+
+	path, err := p.parseIndexAccessedPath()
+	if err != nil {
+		return stype, nil, err
+	}
+	if tok := p.currentToken(); tok == Identifier || IsLocationSpecifier(tok) {
+		return VariableDeclarationStatementType, path, nil
+	}
+	return ExpressionStatementType, path, nil
+}
+
+func (p *Parser) parseIndexAccessedPath() (iap *indexAccessedPath, err error) {
+	iap = &indexAccessedPath{}
+	tok := p.currentToken()
+	if tok == Identifier {
+		iap.path = append(iap.path, IdentifierExpression{p.getLiteralAndAdvance()})
+		for p.currentToken() == Period {
+			p.next()
+			name, err := p.expectIdentifierTokenOrAddress()
+			if err != nil {
+				return iap, err
+			}
+			iap.path = append(iap.path, IdentifierExpression{name})
+		}
+	} else {
+		firstSize, secondSize := p.currentTokenInfo()
+		iap.path = append(iap.path, ElementaryTypeName{tok, firstSize, secondSize})
+		p.next()
+	}
+
+	for p.currentToken() == LBrack {
+		p.next()
+		var index pathIndex
+		if tok := p.currentToken(); tok != RBrack && tok != Colon {
+			index.start, err = p.parseExpression()
+			if err != nil {
+				return iap, err
+			}
+		}
+		if p.currentToken() == Colon {
+			p.next()
+			index.isRange = true
+			if p.currentToken() != RBrack {
+				index.end, err = p.parseExpression()
+				if err != nil {
+					return iap, err
+				}
+			}
+		}
+		err = p.expectToken(RBrack)
+		if err != nil {
+			return iap, err
+		}
+		iap.indices = append(iap.indices, index)
+	}
+	return
+}
+
+func (p *Parser) typeNameFromIndexAccessStructure(iap *indexAccessedPath) (t TypeName, err error) {
+	if typ, ok := iap.path[0].(ElementaryTypeName); ok {
+		t = typ
+	} else {
+		var u UserDefinedTypeName
+		for _, e := range iap.path {
+			u.NamePath = append(u.NamePath, e.(IdentifierExpression).Literal)
+		}
+		t = u
+	}
+	for _, index := range iap.indices {
+		if index.isRange {
+			return t, errors.New("Expected array length expression")
+		}
+		t = ArrayTypeName{BaseType: t, Length: index.start}
+	}
+	return
+}
+
+func (p *Parser) expressionFromIndexAccessStructure(iap *indexAccessedPath) (e Expression) {
+	e = iap.path[0]
+	for _, member := range iap.path[1:] {
+		e = MemberAccess{Expression: e, MemberName: member.(IdentifierExpression).Literal}
+	}
+	for _, index := range iap.indices {
+		if index.isRange {
+			e = IndexRangeAccess{BaseExpression: e, StartExpression: index.start, EndExpression: index.end}
+		} else {
+			e = IndexAccess{BaseExpression: e, IndexExpression: index.start}
+		}
+	}
+	return
+}
+
+func (p *Parser) parseExpressionStatement() (s Statement, err error) {
+	s.Expression, err = p.parseExpression()
+	return
+}
+
+func (p *Parser) parseBinaryExpression(minPrecedence int, partial Expression) (e Expression, err error) {
+	e, err = p.parseUnaryExpression(partial)
+	if err != nil {
+		return e, err
+	}
+
+	// TODO: do we need tokenPrecence() or is this info stored with Token in Scanner
+	precedence := TokenPrecedence(p.currentToken())
+	for ; precedence >= minPrecedence; precedence-- {
+		for TokenPrecedence(p.currentToken()) == precedence {
+			op := p.currentToken()
+			p.next()
+			right, err := p.parseBinaryExpression(precedence+1, nil)
+			if err != nil {
+				return e, err
+			}
+			e = BinaryOperation{e, op, right}
+		}
+	}
+	return
+}
+
+func (p *Parser) parseUnaryExpression(partial Expression) (e Expression, err error) {
+	u := UnaryOperation{}
+	u.Token = p.currentToken()
+
+	if partial == nil && (IsUnaryOp(u.Token) || IsCountOp(u.Token)) {
+		// prefix expression
+		u.IsPrefix = true
+		p.next()
+		u.SubExpression, err = p.parseUnaryExpression(nil)
+		return u, err
+	} else {
+		u.SubExpression, err = p.parseLeftHandSideExpression(partial)
+		if err != nil {
+			return u, err
+		}
+		tok := p.currentToken()
+		if !IsCountOp(tok) {
+			return u.SubExpression, nil
+		}
+		// postfix expression
+		u.Token = tok
+		p.next()
+	}
+	return u, nil
+}
+
+func (p *Parser) parseLeftHandSideExpression(partial Expression) (e Expression, err error) {
+	tok := p.currentToken()
+	if partial != nil {
+		e = partial
+	} else if tok == New {
+		p.next()
+		typ, err := p.parseTypeName(false)
+		if err != nil {
+			return e, err
+		}
+		e = NewExpression{typ}
+	} else {
 		e, err = p.parsePrimaryExpression()
 		if err != nil {
 			return e, err
@@ -558,23 +1474,60 @@ out:
 		switch p.currentToken() {
 		case LBrack:
 			p.next()
-			if p.currentToken() != RBrack {
-				index, err := p.parseExpression()
+			var index, end Expression
+			if tok := p.currentToken(); tok != RBrack && tok != Colon {
+				index, err = p.parseExpression()
 				if err != nil {
 					return e, err
 				}
-				err = p.expectToken(RBrack)
-				if err != nil {
-					return e, err
+			}
+			if p.currentToken() == Colon {
+				p.next()
+				if p.currentToken() != RBrack {
+					end, err = p.parseExpression()
+					if err != nil {
+						return e, err
+					}
 				}
-				return index, errors.New("index access not yet implemented")
+				e = IndexRangeAccess{BaseExpression: e, StartExpression: index, EndExpression: end}
+			} else {
+				e = IndexAccess{BaseExpression: e, IndexExpression: index}
+			}
+			err = p.expectToken(RBrack)
+			if err != nil {
+				return e, err
+			}
+		case LBrace:
+			// call options are always of the form {name: value}, anything
+			// else is a block following the expression, e.g. in a try statement
+			if p.s.peekNextToken() != Identifier || p.s.peekNextNextToken() != Colon {
+				break out
 			}
+			opts := FunctionCallOptions{Expression: e}
+			opts.Options, opts.Names, err = p.parseNamedArguments()
+			if err != nil {
+				return opts, err
+			}
+			e = opts
 		case Period:
 			p.next()
-			return e, errors.New("member access not yet implemented")
+			name, err := p.expectIdentifierTokenOrAddress()
+			if err != nil {
+				return e, err
+			}
+			e = MemberAccess{Expression: e, MemberName: name}
 		case LParen:
 			p.next()
-			return e, errors.New("function calls not yet implemented")
+			call := FunctionCall{Expression: e}
+			call.Arguments, call.Names, err = p.parseFunctionCallArguments()
+			if err != nil {
+				return call, err
+			}
+			err = p.expectToken(RParen)
+			if err != nil {
+				return call, err
+			}
+			e = call
 		default:
 			break out
 		}
@@ -586,28 +1539,122 @@ func (p *Parser) parsePrimaryExpression() (e Expression, err error) {
 	tok := p.currentToken()
 	switch tok {
 	case TrueLiteral, FalseLiteral:
-		//l := Literal{}
-		// Expression.Literal = p.getLiteralAndAdvance() // or currenetLIteral ??
-		// expression.Token = tok
+		lit := p.getLiteralAndAdvance()
+		return Literal{Kind: BoolLiteralKind, Value: lit, Raw: lit}, nil
 	case Number:
-		return e, errors.New("number expression not yet implemented")
-		//fallthrough
+		lit := p.getLiteralAndAdvance()
+		l := Literal{Kind: NumberLiteralKind, Value: lit, Raw: lit}
+		if IsSubDenomination(p.currentToken()) {
+			l.SubDenomination = p.currentToken()
+			p.next()
+		}
+		return l, nil
 	case StringLiteral:
-		// expression.Literal = p.getLiteralAndAdvance()
-		// expression.Token = tok
+		lit, raw := p.parseStringLiteralSequence()
+		return Literal{Kind: StringLiteralKind, Value: lit, Raw: raw}, nil
+	case HexStringLiteral:
+		lit, raw := p.parseStringLiteralSequence()
+		return Literal{Kind: HexStringLiteralKind, Value: lit, Raw: raw}, nil
+	case UnicodeStringLiteral:
+		lit, raw := p.parseStringLiteralSequence()
+		return Literal{Kind: UnicodeStringLiteralKind, Value: lit, Raw: raw}, nil
 	case Identifier:
 		l := p.getLiteralAndAdvance()
 		return IdentifierExpression{l}, err
-	case LParen, LBrack:
-		return e, errors.New("tuples / paranthesized expression not yet implemented")
+	case LParen:
+		return p.parseTupleExpression()
+	case LBrack:
+		return p.parseInlineArrayExpression()
 	default:
-		if isElementaryTypeName(tok) {
+		if IsElementaryTypeName(tok) {
+			// used in type conversions like uint(x)
 			firstSize, secondSize := p.currentTokenInfo()
+			p.next()
 			return ElementaryTypeName{tok, firstSize, secondSize}, nil
 		} else {
 			return e, errors.New("Expected primary expression")
 		}
 	}
+}
+
+func (p *Parser) parseTupleExpression() (t TupleExpression, err error) {
+	err = p.expectToken(LParen)
+	if err != nil {
+		return
+	}
+	return p.parseTupleComponents(nil, nil)
+}
+
+func (p *Parser) parseInlineArrayExpression() (t TupleExpression, err error) {
+	err = p.expectToken(LBrack)
+	if err != nil {
+		return
+	}
+	t.IsInlineArray = true
+	if p.currentToken() != RBrack {
+		for {
+			if tok := p.currentToken(); tok == Comma || tok == RBrack {
+				return t, errors.New("Expected expression (inline array elements cannot be omitted)")
+			}
+			e, err := p.parseExpression()
+			if err != nil {
+				return t, err
+			}
+			t.Components = append(t.Components, e)
+			if p.currentToken() != Comma {
+				break
+			}
+			p.next()
+		}
+	}
+	err = p.expectToken(RBrack)
+	return
+}
+
+// parseTupleComponents parses the remaining components of a tuple whose
+// opening parenthesis and any leading empty components have been consumed.
+// partial is the already parsed start of the next component, if any
+func (p *Parser) parseTupleComponents(components []Expression, partial Expression) (t TupleExpression, err error) {
+	t.Components = components
+	if len(components) > 0 || partial != nil || p.currentToken() != RParen {
+		for {
+			if tok := p.currentToken(); partial != nil || (tok != Comma && tok != RParen) {
+				e, err := p.parseExpressionWithLookAhead(partial)
+				if err != nil {
+					return t, err
+				}
+				partial = nil
+				t.Components = append(t.Components, e)
+			} else {
+				// omitted component, e.g. (a, , c)
+				t.Components = append(t.Components, nil)
+			}
+			if p.currentToken() == RParen {
+				break
+			}
+			err = p.expectToken(Comma)
+			if err != nil {
+				return t, err
+			}
+		}
+	}
+	err = p.expectToken(RParen)
+	return
+}
+
+// parseStringLiteralSequence concatenates adjacent string literals of the
+// same kind, e.g. "abc" "def"
+// parseStringLiteralSequence returns the concatenated contents of adjacent
+// string literals of the same kind and their source text
+func (p *Parser) parseStringLiteralSequence() (lit, raw string) {
+	tok := p.currentToken()
+	lit, raw = p.currentLiteral(), p.s.currentRaw()
+	for p.s.peekNextToken() == tok {
+		p.next()
+		lit += p.currentLiteral()
+		raw += " " + p.s.currentRaw()
+	}
+	p.next()
 	return
 }
 
@@ -638,6 +1685,16 @@ func (p *Parser) expectIdentifierToken() (lit string, err error) {
 	return
 }
 
+// expectIdentifierTokenOrAddress also accepts 'address' which is a valid
+// member name, e.g. this.address
+func (p *Parser) expectIdentifierTokenOrAddress() (lit string, err error) {
+	if p.currentToken() == Address {
+		p.next()
+		return Address.String(), nil
+	}
+	return p.expectIdentifierToken()
+}
+
 func (p *Parser) currentToken() Token {
 	return p.s.currentToken()
 }
@@ -646,51 +1703,68 @@ func (p *Parser) currentLiteral() string {
 	return p.s.currentLiteral()
 }
 
+// currentTokenInfo returns the sizes of the current type token, e.g. 8 for
+// uint8 or 128 and 18 for fixed128x18
 func (p *Parser) currentTokenInfo() (int, int) {
-	_ = p.currentLiteral()
-	//baseType = // position of M
-	//tok := keywordByName(baseType)
-	//switch tok {
-	//case Bytes:
-	//return m, 0
-	//case Uint, Int:
-	//return m, 0
-	//case UFixed, Fixed:
-	//return m, n
-	//}
+	return p.s.currentTokenInfo()
+}
 
-	return 0, 0
+// takeComments returns the comments before the current token which are not
+// part of the AST yet
+func (p *Parser) takeComments() (comments []Node) {
+	if !p.keepComments {
+		return
+	}
+	src := p.s.src.Bytes()
+	start := p.s.curTok.span.Start
+	for len(p.s.comments) > 0 && p.s.comments[0].Start < start {
+		span := p.s.comments[0]
+		p.s.comments = p.s.comments[1:]
+		if span.Start < p.lastEnd {
+			// inside of the previous statement or declaration
+			p.innerComments = append(p.innerComments, span)
+			continue
+		}
+		trailing := p.lastEnd > 0 && !bytes.ContainsRune(src[p.lastEnd:span.Start], '\n')
+		comments = append(comments, Comment{Text: string(src[span.Start:span.End]), Trailing: trailing})
+	}
+	return
 }
 
 // Advance the scanner
 func (p *Parser) next() Token {
+	p.lastEnd = p.s.curTok.span.End
+	if p.syntaxStack != nil {
+		top := p.syntaxStack[len(p.syntaxStack)-1]
+		top.Children = append(top.Children, p.s.currentSyntaxToken())
+	}
 	return p.s.next()
 }
 
 type StatementType int
 
 const (
-	ExpressionStatement StatementType = iota
-	VariableDeclarationStatement
-	IndexAccessStructure
+	ExpressionStatementType StatementType = iota
+	VariableDeclarationStatementType
+	IndexAccessStructureType
 )
 
 // Distinguish between variable declaration (and potentially assignment) and expression statement
 func (p *Parser) peekStatementType() StatementType {
 	tok := p.currentToken()
-	mightBeTypeName := (isElementaryTypeName(tok) || tok == Identifier)
+	mightBeTypeName := (IsElementaryTypeName(tok) || tok == Identifier)
 
 	if (tok == Mapping) || (tok == Var) {
-		return VariableDeclarationStatement
+		return VariableDeclarationStatementType
 	}
 	if mightBeTypeName {
 		nextTok := p.s.peekNextToken()
-		if (nextTok == Identifier) || isLocationSpecifier(nextTok) {
-			return VariableDeclarationStatement
+		if (nextTok == Identifier) || IsLocationSpecifier(nextTok) {
+			return VariableDeclarationStatementType
 		}
 		if (nextTok == LBrack) || (nextTok == Period) {
-			return IndexAccessStructure
+			return IndexAccessStructureType
 		}
 	}
-	return ExpressionStatement
+	return ExpressionStatementType
 }
@@ -1,6 +1,8 @@
 package solparse
 
 import (
+	"bytes"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -11,7 +13,7 @@ func TestParser(t *testing.T) {
 		name   string
 		source string
 		valid  bool
-		fn     func(ContractDefinition, *testing.T)
+		fn     func(*SourceUnit, *testing.T)
 	}{
 		{
 			name: "smoke test",
@@ -92,17 +94,1055 @@ func TestParser(t *testing.T) {
 		`,
 			valid: true,
 		},
+
+		{
+			name: "special functions",
+			source: `contract test {
+			constructor(uint a) public {}
+			receive() external payable {}
+			fallback(bytes calldata input) external returns (bytes memory) {}
+			function f() public view onlyOwner {}
+			}
+		`,
+			valid: true,
+			fn: func(su *SourceUnit, t *testing.T) {
+				cd := su.Nodes[0].(ContractDefinition)
+				kinds := []FunctionKind{ConstructorFunction, ReceiveFunction, FallbackFunction, OrdinaryFunction}
+				for i, k := range kinds {
+					f := cd.SubNodes[i].(FunctionDefinition)
+					if f.Kind != k {
+						t.Errorf("function %d: expected kind %d got %d", i, k, f.Kind)
+					}
+				}
+				if !cd.SubNodes[0].(FunctionDefinition).IsConstructor {
+					t.Error("constructor should set IsConstructor")
+				}
+				if cd.SubNodes[2].(FunctionDefinition).Paramaters.Paramaters[0].Location != "calldata" {
+					t.Error("expected calldata location on fallback parameter")
+				}
+				if m := cd.SubNodes[3].(FunctionDefinition).Modifiers; len(m) != 1 || m[0].Name != "onlyOwner" {
+					t.Errorf("expected onlyOwner modifier got %v", m)
+				}
+			},
+		},
+
+		{
+			name: "legacy constructor and fallback",
+			source: `contract test {
+			function test() {}
+			function() payable {}
+			}
+		`,
+			valid: true,
+			fn: func(su *SourceUnit, t *testing.T) {
+				cd := su.Nodes[0].(ContractDefinition)
+				if f := cd.SubNodes[0].(FunctionDefinition); f.Kind != ConstructorFunction || !f.IsConstructor {
+					t.Error("function named after contract should be a constructor")
+				}
+				if f := cd.SubNodes[1].(FunctionDefinition); f.Kind != FallbackFunction || !f.IsLegacyFallback {
+					t.Error("unnamed function should be a legacy fallback")
+				}
+			},
+		},
+
+		{
+			name: "receive and fallback as names",
+			source: `contract test {
+			uint receive;
+			function fallback() {}
+			}
+		`,
+			valid: true,
+			fn: func(su *SourceUnit, t *testing.T) {
+				cd := su.Nodes[0].(ContractDefinition)
+				if f := cd.SubNodes[1].(FunctionDefinition); f.Kind != OrdinaryFunction {
+					t.Error("function named fallback should be an ordinary function")
+				}
+			},
+		},
+
+		{
+			name: "duplicate visibility",
+			source: `contract test {
+			function f() public external {}
+			}
+		`,
+			valid: false,
+		},
+
+		{
+			name: "free functions and file level constants",
+			source: `
+			uint constant X = y;
+			function f() pure returns (uint) {}
+			contract test {
+			function g() {}
+			}
+			library lib {
+			function h(uint a) internal returns (uint) {}
+			}
+		`,
+			valid: true,
+			fn: func(su *SourceUnit, t *testing.T) {
+				if len(su.Nodes) != 4 {
+					t.Fatalf("expected 4 source unit members got %d", len(su.Nodes))
+				}
+				if vd := su.Nodes[0].(VariableDeclaration); vd.Identifier != "X" || !vd.IsDeclaredConst {
+					t.Errorf("expected constant X got %+v", vd)
+				}
+				if fd := su.Nodes[1].(FunctionDefinition); fd.Name != "f" || !fd.IsPure {
+					t.Errorf("expected pure free function f got %+v", fd)
+				}
+				if cd := su.Nodes[3].(ContractDefinition); cd.Name != "lib" || !cd.IsLibrary {
+					t.Errorf("expected library lib got %+v", cd)
+				}
+			},
+		},
+
+		{
+			name:   "non constant file level variable",
+			source: `uint x;`,
+			valid:  false,
+		},
+
+		{
+			name:   "free function with visibility",
+			source: `function f() public {}`,
+			valid:  false,
+		},
+
+		{
+			name:   "free function without body",
+			source: `function f();`,
+			valid:  false,
+		},
+
+		{
+			name: "control flow statements",
+			source: `contract test {
+			function f(uint n) {
+				if (a < b) { } else if (a >= b) { } else { }
+				while (x != y) { break; }
+				do { continue; } while (i <= n);
+				for (; i < n; i++) { }
+				for (;;) {}
+				if (a) if (b) x; else y;
+			}
+			}
+		`,
+			valid: true,
+			fn: func(su *SourceUnit, t *testing.T) {
+				body := su.Nodes[0].(ContractDefinition).SubNodes[0].(FunctionDefinition).Block.(Block)
+				if _, ok := body.Statements[0].(IfStatement).FalseBody.(IfStatement); !ok {
+					t.Error("expected else if chain")
+				}
+				if _, ok := body.Statements[2].(DoWhileStatement); !ok {
+					t.Errorf("expected do while statement got %T", body.Statements[2])
+				}
+				loop := body.Statements[3].(ForStatement)
+				if loop.InitializationExpression != nil || loop.Condition == nil || loop.LoopExpression == nil {
+					t.Errorf("unexpected for statement parts %+v", loop)
+				}
+				if u, ok := loop.LoopExpression.(UnaryOperation); !ok || u.Token != Inc || u.IsPrefix {
+					t.Errorf("expected postfix increment got %+v", loop.LoopExpression)
+				}
+				if loop := body.Statements[4].(ForStatement); loop.Condition != nil {
+					t.Error("expected empty for condition")
+				}
+				outer := body.Statements[5].(IfStatement)
+				if outer.FalseBody != nil || outer.TrueBody.(IfStatement).FalseBody == nil {
+					t.Error("else should bind to the innermost if")
+				}
+			},
+		},
+
+		{
+			name: "do while missing semicolon",
+			source: `contract test {
+			function f() { do { } while (x) }
+			}
+		`,
+			valid: false,
+		},
+
+		{
+			name: "return statements",
+			source: `contract test {
+			function f() returns (uint, uint) {
+				if (a) return;
+				if (b) return a;
+				return (a, b);
+			}
+			}
+		`,
+			valid: true,
+			fn: func(su *SourceUnit, t *testing.T) {
+				body := su.Nodes[0].(ContractDefinition).SubNodes[0].(FunctionDefinition).Block.(Block)
+				if r := body.Statements[0].(IfStatement).TrueBody.(ReturnStatement); r.Expression != nil {
+					t.Errorf("expected bare return got %+v", r)
+				}
+				if r := body.Statements[1].(IfStatement).TrueBody.(ReturnStatement); r.Expression != (IdentifierExpression{"a"}) {
+					t.Errorf("expected return of a got %+v", r)
+				}
+				tuple, ok := body.Statements[2].(ReturnStatement).Expression.(TupleExpression)
+				if !ok || len(tuple.Components) != 2 {
+					t.Errorf("expected return of a two component tuple got %+v", body.Statements[2])
+				}
+			},
+		},
+
+		{
+			name: "variable declaration statements",
+			source: `contract test {
+			function f() {
+				uint x = y;
+				MyStruct memory s;
+				(uint a, , bool c) = t;
+				(, string memory d) = t;
+				var (e, , g) = t;
+				var h = x;
+				(a, , c);
+				for (uint i = x; i < y; i++) {}
+			}
+			}
+		`,
+			valid: true,
+			fn: func(su *SourceUnit, t *testing.T) {
+				body := su.Nodes[0].(ContractDefinition).SubNodes[0].(FunctionDefinition).Block.(Block)
+				s := body.Statements[0].(VariableDeclarationStatement)
+				if len(s.Declarations) != 1 || s.Declarations[0].Identifier != "x" || s.InitialValue == nil {
+					t.Errorf("unexpected declaration %+v", s)
+				}
+				s = body.Statements[1].(VariableDeclarationStatement)
+				if d := s.Declarations[0]; d.Location != "memory" || d.Type.(UserDefinedTypeName).NamePath[0] != "MyStruct" {
+					t.Errorf("unexpected struct declaration %+v", d)
+				}
+				s = body.Statements[2].(VariableDeclarationStatement)
+				if len(s.Declarations) != 3 || s.Declarations[1] != nil || s.Declarations[2].Identifier != "c" {
+					t.Errorf("unexpected tuple declaration %+v", s)
+				}
+				s = body.Statements[3].(VariableDeclarationStatement)
+				if len(s.Declarations) != 2 || s.Declarations[0] != nil || s.Declarations[1].Location != "memory" {
+					t.Errorf("unexpected tuple declaration %+v", s)
+				}
+				s = body.Statements[4].(VariableDeclarationStatement)
+				if len(s.Declarations) != 3 || s.Declarations[0].Type != nil || s.Declarations[1] != nil {
+					t.Errorf("unexpected var tuple declaration %+v", s)
+				}
+				tuple := body.Statements[6].(Statement).Expression.(TupleExpression)
+				if len(tuple.Components) != 3 || tuple.Components[1] != nil {
+					t.Errorf("unexpected tuple expression %+v", tuple)
+				}
+				if _, ok := body.Statements[7].(ForStatement).InitializationExpression.(VariableDeclarationStatement); !ok {
+					t.Error("expected variable declaration in for loop initialization")
+				}
+			},
+		},
+
+		{
+			name: "tuple declaration without value",
+			source: `contract test {
+			function f() { (uint a, uint b); }
+			}
+		`,
+			valid: false,
+		},
+
+		{
+			name: "var in parameter list",
+			source: `contract test {
+			function f(var a) {}
+			}
+		`,
+			valid: false,
+		},
+
+		{
+			name: "assignments",
+			source: `contract test {
+			function f() {
+				a = b = c;
+				x += y;
+				z >>= w;
+				(m, n) = (n, m);
+				m |= n + o;
+			}
+			}
+		`,
+			valid: true,
+			fn: func(su *SourceUnit, t *testing.T) {
+				body := su.Nodes[0].(ContractDefinition).SubNodes[0].(FunctionDefinition).Block.(Block)
+				a := body.Statements[0].(Statement).Expression.(Assignment)
+				if a.LeftHandSide != (IdentifierExpression{"a"}) {
+					t.Errorf("unexpected left hand side %+v", a.LeftHandSide)
+				}
+				if inner, ok := a.RightHandSide.(Assignment); !ok || inner.LeftHandSide != (IdentifierExpression{"b"}) {
+					t.Errorf("assignment should be right associative got %+v", a.RightHandSide)
+				}
+				ops := []Token{Assign, AssignAdd, AssignSar, Assign, AssignBitOr}
+				for i, op := range ops {
+					if a := body.Statements[i].(Statement).Expression.(Assignment); a.AssignmentOperator != op {
+						t.Errorf("statement %d: expected '%s' got '%s'", i, op, a.AssignmentOperator)
+					}
+				}
+				if _, ok := body.Statements[4].(Statement).Expression.(Assignment).RightHandSide.(BinaryOperation); !ok {
+					t.Error("expected binary operation on the right hand side")
+				}
+			},
+		},
+
+		{
+			name: "assignment without right hand side",
+			source: `contract test {
+			function f() { a = ; }
+			}
+		`,
+			valid: false,
+		},
+
+		{
+			name: "conditional expressions",
+			source: `contract test {
+			function f() {
+				m = a < b ? a : b;
+				n = a || b ? c : d ? e : g;
+			}
+			}
+		`,
+			valid: true,
+			fn: func(su *SourceUnit, t *testing.T) {
+				body := su.Nodes[0].(ContractDefinition).SubNodes[0].(FunctionDefinition).Block.(Block)
+				c, ok := body.Statements[0].(Statement).Expression.(Assignment).RightHandSide.(ConditionalExpression)
+				if !ok {
+					t.Fatalf("expected conditional on the right hand side of the assignment")
+				}
+				if cond, ok := c.Condition.(BinaryOperation); !ok || cond.Operation != LessThan {
+					t.Errorf("unexpected condition %+v", c.Condition)
+				}
+				if c.TrueExpression != (IdentifierExpression{"a"}) || c.FalseExpression != (IdentifierExpression{"b"}) {
+					t.Errorf("unexpected branches %+v", c)
+				}
+				c = body.Statements[1].(Statement).Expression.(Assignment).RightHandSide.(ConditionalExpression)
+				if cond, ok := c.Condition.(BinaryOperation); !ok || cond.Operation != Or {
+					t.Errorf("|| should bind tighter than ?: got %+v", c.Condition)
+				}
+				if _, ok := c.FalseExpression.(ConditionalExpression); !ok {
+					t.Errorf("nested conditional should be right associative got %+v", c.FalseExpression)
+				}
+			},
+		},
+
+		{
+			name: "conditional without colon",
+			source: `contract test {
+			function f() { m = a ? b; }
+			}
+		`,
+			valid: false,
+		},
+
+		{
+			name: "function calls",
+			source: `contract test {
+			function f() {
+				f(a, b + c);
+				g();
+				r = h({x: a, y: b});
+				x = uint(y);
+				f(a)(b);
+			}
+			}
+		`,
+			valid: true,
+			fn: func(su *SourceUnit, t *testing.T) {
+				body := su.Nodes[0].(ContractDefinition).SubNodes[0].(FunctionDefinition).Block.(Block)
+				call := body.Statements[0].(Statement).Expression.(FunctionCall)
+				if call.Expression != (IdentifierExpression{"f"}) || len(call.Arguments) != 2 || call.Names != nil {
+					t.Errorf("unexpected call %+v", call)
+				}
+				if call := body.Statements[1].(Statement).Expression.(FunctionCall); len(call.Arguments) != 0 {
+					t.Errorf("expected call without arguments got %+v", call)
+				}
+				call = body.Statements[2].(Statement).Expression.(Assignment).RightHandSide.(FunctionCall)
+				if len(call.Names) != 2 || call.Names[0] != "x" || call.Names[1] != "y" || len(call.Arguments) != 2 {
+					t.Errorf("unexpected named argument call %+v", call)
+				}
+				call = body.Statements[3].(Statement).Expression.(Assignment).RightHandSide.(FunctionCall)
+				if typ, ok := call.Expression.(ElementaryTypeName); !ok || typ.Token != Uint {
+					t.Errorf("expected type conversion got %+v", call)
+				}
+				call = body.Statements[4].(Statement).Expression.(FunctionCall)
+				if _, ok := call.Expression.(FunctionCall); !ok {
+					t.Errorf("expected chained call got %+v", call)
+				}
+			},
+		},
+
+		{
+			name: "missing parameter name in named args without numbers",
+			source: `contract test {
+			function b() returns (uint r) { r = a({: x, : y}); }
+			}
+		`,
+			valid: false,
+		},
+
+		{
+			name: "missing argument in named args without numbers",
+			source: `contract test {
+			function b() returns (uint r) { r = a({a: , b: }); }
+			}
+		`,
+			valid: false,
+		},
+
+		{
+			name: "trailing comma in named args",
+			source: `contract test {
+			function b() { a({a: x, }); }
+			}
+		`,
+			valid: false,
+		},
+
+		{
+			name: "unterminated argument list",
+			source: `contract test {
+			function b() { a(x, y; }
+			}
+		`,
+			valid: false,
+		},
+
+		{
+			name: "member and index access",
+			source: `contract test {
+			uint[] public arr;
+			Lib.S[][] nested;
+			function f(bytes calldata data) {
+				balances[msg.sender] = x;
+				a.b.c = d;
+				Lib.S memory s;
+				uint[] memory list = arr;
+				x.y[i].z = w;
+				h = data[x:];
+				h = data[:y];
+				(a[i], b) = t;
+				(uint[] memory m, uint n) = t;
+				g = abi.decode(data, (uint[]));
+			}
+			}
+		`,
+			valid: true,
+			fn: func(su *SourceUnit, t *testing.T) {
+				cd := su.Nodes[0].(ContractDefinition)
+				if typ, ok := cd.SubNodes[0].(VariableDeclaration).Type.(ArrayTypeName); !ok || typ.Length != nil {
+					t.Errorf("expected dynamic array state variable got %+v", cd.SubNodes[0])
+				}
+				nested := cd.SubNodes[1].(VariableDeclaration).Type.(ArrayTypeName).BaseType.(ArrayTypeName)
+				if path := nested.BaseType.(UserDefinedTypeName).NamePath; len(path) != 2 || path[1] != "S" {
+					t.Errorf("unexpected nested array base type %+v", nested)
+				}
+
+				body := cd.SubNodes[2].(FunctionDefinition).Block.(Block)
+				index := body.Statements[0].(Statement).Expression.(Assignment).LeftHandSide.(IndexAccess)
+				if m := index.IndexExpression.(MemberAccess); m.MemberName != "sender" || m.Expression != (IdentifierExpression{"msg"}) {
+					t.Errorf("unexpected index %+v", index.IndexExpression)
+				}
+				member := body.Statements[1].(Statement).Expression.(Assignment).LeftHandSide.(MemberAccess)
+				if member.MemberName != "c" || member.Expression.(MemberAccess).MemberName != "b" {
+					t.Errorf("unexpected member access %+v", member)
+				}
+				decl := body.Statements[2].(VariableDeclarationStatement).Declarations[0]
+				if path := decl.Type.(UserDefinedTypeName).NamePath; len(path) != 2 || decl.Location != "memory" {
+					t.Errorf("unexpected struct declaration %+v", decl)
+				}
+				decl = body.Statements[3].(VariableDeclarationStatement).Declarations[0]
+				if typ := decl.Type.(ArrayTypeName); typ.BaseType.(ElementaryTypeName).Token != Uint {
+					t.Errorf("unexpected array declaration %+v", decl)
+				}
+				member = body.Statements[4].(Statement).Expression.(Assignment).LeftHandSide.(MemberAccess)
+				if _, ok := member.Expression.(IndexAccess); !ok || member.MemberName != "z" {
+					t.Errorf("unexpected member access %+v", member)
+				}
+				slice := body.Statements[5].(Statement).Expression.(Assignment).RightHandSide.(IndexRangeAccess)
+				if slice.StartExpression == nil || slice.EndExpression != nil {
+					t.Errorf("unexpected slice %+v", slice)
+				}
+				slice = body.Statements[6].(Statement).Expression.(Assignment).RightHandSide.(IndexRangeAccess)
+				if slice.StartExpression != nil || slice.EndExpression == nil {
+					t.Errorf("unexpected slice %+v", slice)
+				}
+				tuple := body.Statements[7].(Statement).Expression.(Assignment).LeftHandSide.(TupleExpression)
+				if _, ok := tuple.Components[0].(IndexAccess); !ok || len(tuple.Components) != 2 {
+					t.Errorf("unexpected tuple %+v", tuple)
+				}
+				decls := body.Statements[8].(VariableDeclarationStatement).Declarations
+				if _, ok := decls[0].Type.(ArrayTypeName); !ok || len(decls) != 2 || decls[0].Identifier != "m" {
+					t.Errorf("unexpected tuple declaration %+v", decls)
+				}
+				call := body.Statements[9].(Statement).Expression.(Assignment).RightHandSide.(FunctionCall)
+				if typ := call.Arguments[1].(TupleExpression).Components[0].(IndexAccess); typ.IndexExpression != nil {
+					t.Errorf("unexpected type expression %+v", typ)
+				}
+			},
+		},
+
+		{
+			name: "slice in type position",
+			source: `contract test {
+			function f() { uint[x:y] a; }
+			}
+		`,
+			valid: false,
+		},
+
+		{
+			name: "member access without name",
+			source: `contract test {
+			function f() { a. = b; }
+			}
+		`,
+			valid: false,
+		},
+
+		{
+			name: "function call options",
+			source: `contract test {
+			function f() {
+				addr.call{value: v, gas: g}(data);
+				(bool ok, ) = to.call{value: amount}(data);
+				while (x) {}
+			}
+			}
+		`,
+			valid: true,
+			fn: func(su *SourceUnit, t *testing.T) {
+				body := su.Nodes[0].(ContractDefinition).SubNodes[0].(FunctionDefinition).Block.(Block)
+				call := body.Statements[0].(Statement).Expression.(FunctionCall)
+				opts, ok := call.Expression.(FunctionCallOptions)
+				if !ok {
+					t.Fatalf("expected call options got %+v", call.Expression)
+				}
+				if len(opts.Names) != 2 || opts.Names[0] != "value" || opts.Names[1] != "gas" || len(opts.Options) != 2 {
+					t.Errorf("unexpected call options %+v", opts)
+				}
+				if m := opts.Expression.(MemberAccess); m.MemberName != "call" {
+					t.Errorf("unexpected call options target %+v", m)
+				}
+				call = body.Statements[1].(VariableDeclarationStatement).InitialValue.(FunctionCall)
+				if opts := call.Expression.(FunctionCallOptions); opts.Names[0] != "value" {
+					t.Errorf("unexpected call options %+v", opts)
+				}
+			},
+		},
+
+		{
+			name: "call option without value",
+			source: `contract test {
+			function f() { addr.call{value: }(data); }
+			}
+		`,
+			valid: false,
+		},
+
+		{
+			name: "new expressions",
+			source: `contract test {
+			function f() {
+				Token t = new Token(a, b);
+				uint[] memory list = new uint[](n);
+				bytes memory buf = new bytes(size);
+				c = new C{salt: s}();
+			}
+			}
+		`,
+			valid: true,
+			fn: func(su *SourceUnit, t *testing.T) {
+				body := su.Nodes[0].(ContractDefinition).SubNodes[0].(FunctionDefinition).Block.(Block)
+				call := body.Statements[0].(VariableDeclarationStatement).InitialValue.(FunctionCall)
+				if n := call.Expression.(NewExpression); n.TypeName.(UserDefinedTypeName).NamePath[0] != "Token" {
+					t.Errorf("unexpected new expression %+v", n)
+				}
+				if len(call.Arguments) != 2 {
+					t.Errorf("expected two constructor arguments got %+v", call.Arguments)
+				}
+				call = body.Statements[1].(VariableDeclarationStatement).InitialValue.(FunctionCall)
+				if typ := call.Expression.(NewExpression).TypeName.(ArrayTypeName); typ.Length != nil {
+					t.Errorf("expected dynamic array type got %+v", typ)
+				}
+				call = body.Statements[2].(VariableDeclarationStatement).InitialValue.(FunctionCall)
+				if typ := call.Expression.(NewExpression).TypeName.(ElementaryTypeName); typ.Token != Bytes {
+					t.Errorf("expected bytes type got %+v", typ)
+				}
+				call = body.Statements[3].(Statement).Expression.(Assignment).RightHandSide.(FunctionCall)
+				opts := call.Expression.(FunctionCallOptions)
+				if _, ok := opts.Expression.(NewExpression); !ok || opts.Names[0] != "salt" {
+					t.Errorf("unexpected salted creation %+v", opts)
+				}
+			},
+		},
+
+		{
+			name: "new without type",
+			source: `contract test {
+			function f() { c = new (); }
+			}
+		`,
+			valid: false,
+		},
+
+		{
+			name: "tuples and inline arrays",
+			source: `contract test {
+			function f() {
+				x = (a + b) * c;
+				(a, , c) = g();
+				y = [a, b, c];
+				z = [[a], [b]];
+			}
+			}
+		`,
+			valid: true,
+			fn: func(su *SourceUnit, t *testing.T) {
+				body := su.Nodes[0].(ContractDefinition).SubNodes[0].(FunctionDefinition).Block.(Block)
+				mul := body.Statements[0].(Statement).Expression.(Assignment).RightHandSide.(BinaryOperation)
+				if paren := mul.Expression.(TupleExpression); len(paren.Components) != 1 || paren.IsInlineArray {
+					t.Errorf("unexpected parenthesized expression %+v", paren)
+				}
+				tuple := body.Statements[1].(Statement).Expression.(Assignment).LeftHandSide.(TupleExpression)
+				if len(tuple.Components) != 3 || tuple.Components[1] != nil {
+					t.Errorf("unexpected tuple %+v", tuple)
+				}
+				array := body.Statements[2].(Statement).Expression.(Assignment).RightHandSide.(TupleExpression)
+				if !array.IsInlineArray || len(array.Components) != 3 {
+					t.Errorf("unexpected inline array %+v", array)
+				}
+				array = body.Statements[3].(Statement).Expression.(Assignment).RightHandSide.(TupleExpression)
+				if inner := array.Components[0].(TupleExpression); !inner.IsInlineArray {
+					t.Errorf("unexpected nested inline array %+v", inner)
+				}
+			},
+		},
+
+		{
+			name: "omitted inline array element",
+			source: `contract test {
+			function f() { y = [a, , c]; }
+			}
+		`,
+			valid: false,
+		},
+
+		{
+			name: "unterminated tuple",
+			source: `contract test {
+			function f() { y = (a, b; }
+			}
+		`,
+			valid: false,
+		},
+
+		{
+			name: "literals",
+			source: `contract test {
+			uint constant fee = 1 gwei;
+			function f() {
+				a = 1 ether;
+				b = 2 days;
+				c = 0x10;
+				d = "abc" 'def';
+				e = true;
+				g = false;
+				h = 1e18;
+			}
+			}
+		`,
+			valid: true,
+			fn: func(su *SourceUnit, t *testing.T) {
+				cd := su.Nodes[0].(ContractDefinition)
+				if l := cd.SubNodes[0].(VariableDeclaration).Value.(Literal); l.SubDenomination != SubGwei {
+					t.Errorf("unexpected literal %+v", l)
+				}
+				body := cd.SubNodes[1].(FunctionDefinition).Block.(Block)
+				expected := []Literal{
+					{NumberLiteralKind, "1", "1", SubEther},
+					{NumberLiteralKind, "2", "2", SubDay},
+					{NumberLiteralKind, "0x10", "0x10", EOS},
+					{StringLiteralKind, "abcdef", `"abc" 'def'`, EOS},
+					{BoolLiteralKind, "true", "true", EOS},
+					{BoolLiteralKind, "false", "false", EOS},
+					{NumberLiteralKind, "1e18", "1e18", EOS},
+				}
+				for i, exp := range expected {
+					l := body.Statements[i].(Statement).Expression.(Assignment).RightHandSide.(Literal)
+					if l != exp {
+						t.Errorf("statement %d: expected %+v got %+v", i, exp, l)
+					}
+				}
+			},
+		},
+
+		{
+			name: "hex string literals",
+			source: `contract test {
+			bytes constant prefix = hex"00" hex'ff_01';
+			}
+		`,
+			valid: true,
+			fn: func(su *SourceUnit, t *testing.T) {
+				l := su.Nodes[0].(ContractDefinition).SubNodes[0].(VariableDeclaration).Value.(Literal)
+				if l.Kind != HexStringLiteralKind || l.Value != "\x00\xff\x01" || l.Raw != `hex"00" hex'ff_01'` {
+					t.Errorf("unexpected hex literal %+v", l)
+				}
+			},
+		},
+
+		{
+			name: "unicode string literals",
+			source: `contract test {
+			string constant greeting = unicode"Hello \u2603 ☃";
+			}
+		`,
+			valid: true,
+			fn: func(su *SourceUnit, t *testing.T) {
+				l := su.Nodes[0].(ContractDefinition).SubNodes[0].(VariableDeclaration).Value.(Literal)
+				if l.Kind != UnicodeStringLiteralKind || l.Value != "Hello ☃ ☃" {
+					t.Errorf("unexpected unicode literal %+v", l)
+				}
+			},
+		},
+
+		{
+			name: "non ascii in regular string literal",
+			source: `contract test {
+			string constant greeting = "Hello ☃";
+			}
+		`,
+			valid: false,
+		},
+
+		{
+			name: "elementary type sizes",
+			source: `contract test {
+			uint8 a;
+			bytes20 b;
+			fixed128x18 c;
+			ufixed64x10 d;
+			uint e;
+			}
+		`,
+			valid: true,
+			fn: func(su *SourceUnit, t *testing.T) {
+				expected := []ElementaryTypeName{
+					{UIntM, 8, 0},
+					{BytesM, 20, 0},
+					{FixedMxN, 128, 18},
+					{UFixedMxN, 64, 10},
+					{Uint, 0, 0},
+				}
+				for i, exp := range expected {
+					typ := su.Nodes[0].(ContractDefinition).SubNodes[i].(VariableDeclaration).Type.(ElementaryTypeName)
+					if typ != exp {
+						t.Errorf("variable %d: expected %+v got %+v", i, exp, typ)
+					}
+				}
+			},
+		},
+
+		{
+			name: "inline assembly",
+			source: `contract test {
+			function f(uint a) returns (uint r) {
+				assembly ("memory-safe") {
+					let x, y := f2(a, 0x20)
+					x, y := f2(y, x)
+					r := add(x, 1:u256)
+					if iszero(r) { revert(0, 0) }
+					switch calldataload(4)
+					case 0 { mstore(0, "abc") }
+					case 1 { sstore(r.slot, true) }
+					default { return(0, 32) }
+					for { let i := 0 } lt(i, 10) { i := add(i, 1) } {
+						if gt(i, 5) { break }
+						continue
+					}
+					function f2(p, q) -> s, t {
+						s := byte(0, p)
+						leave
+					}
+				}
+			}
+			}
+		`,
+			valid: true,
+			fn: func(su *SourceUnit, t *testing.T) {
+				body := su.Nodes[0].(ContractDefinition).SubNodes[0].(FunctionDefinition).Block.(Block)
+				a := body.Statements[0].(InlineAssembly)
+				if len(a.Flags) != 1 || a.Flags[0] != "memory-safe" {
+					t.Errorf("unexpected flags %v", a.Flags)
+				}
+				stmts := a.Body.Statements
+				if len(stmts) != 7 {
+					t.Fatalf("expected 7 statements got %d", len(stmts))
+				}
+				decl := stmts[0].(YulVariableDeclaration)
+				if len(decl.Variables) != 2 || decl.Value.(YulFunctionCall).FunctionName != "f2" {
+					t.Errorf("unexpected declaration %+v", decl)
+				}
+				if assign := stmts[1].(YulAssignment); len(assign.VariableNames) != 2 {
+					t.Errorf("unexpected assignment %+v", assign)
+				}
+				lit := stmts[2].(YulAssignment).Value.(YulFunctionCall).Arguments[1].(YulLiteral)
+				if lit.Value != "1" || lit.Type != "u256" {
+					t.Errorf("unexpected typed literal %+v", lit)
+				}
+				sw := stmts[4].(YulSwitch)
+				if len(sw.Cases) != 3 || sw.Cases[2].Value != nil {
+					t.Errorf("unexpected switch %+v", sw)
+				}
+				store := sw.Cases[1].Body.Statements[0].(YulExpressionStatement).Expression.(YulFunctionCall)
+				if store.Arguments[0].(YulIdentifier).Name != "r.slot" {
+					t.Errorf("unexpected identifier %+v", store.Arguments[0])
+				}
+				loop := stmts[5].(YulForLoop)
+				if _, ok := loop.Body.Statements[1].(YulContinue); !ok {
+					t.Errorf("expected continue got %+v", loop.Body.Statements[1])
+				}
+				fn := stmts[6].(YulFunctionDefinition)
+				if fn.Name != "f2" || len(fn.Parameters) != 2 || len(fn.ReturnVariables) != 2 {
+					t.Errorf("unexpected function definition %+v", fn)
+				}
+				if _, ok := fn.Body.Statements[1].(YulLeave); !ok {
+					t.Errorf("expected leave got %+v", fn.Body.Statements[1])
+				}
+			},
+		},
+
+		{
+			name: "assembly dialect",
+			source: `contract test {
+			function f() { assembly "evmasm" { pop(1) } }
+			}
+		`,
+			valid: true,
+		},
+
+		{
+			name: "empty assembly flags",
+			source: `contract test {
+			function f() { assembly () { } }
+			}
+		`,
+			valid: false,
+		},
+
+		{
+			name: "assembly literal statement",
+			source: `contract test {
+			function f() { assembly { 1 } }
+			}
+		`,
+			valid: false,
+		},
+
+		{
+			name: "assembly break outside for loop",
+			source: `contract test {
+			function f() { assembly { break } }
+			}
+		`,
+			valid: false,
+		},
+
+		{
+			name: "assembly leave outside function",
+			source: `contract test {
+			function f() { assembly { leave } }
+			}
+		`,
+			valid: false,
+		},
+
+		{
+			name: "assembly switch without cases",
+			source: `contract test {
+			function f() { assembly { switch 1 } }
+			}
+		`,
+			valid: false,
+		},
+
+		{
+			name: "assembly case after default",
+			source: `contract test {
+			function f() { assembly { switch 1 default { } case 1 { } } }
+			}
+		`,
+			valid: false,
+		},
+
+		{
+			name: "assembly function in for loop init",
+			source: `contract test {
+			function f() { assembly { for { function g() {} } 1 {} {} } }
+			}
+		`,
+			valid: false,
+		},
+
+		{
+			name: "try catch",
+			source: `contract test {
+			function f() {
+				try ext.f{value: 1 ether}(a) returns (uint v, bytes memory) {
+					x = v;
+				} catch Error(string memory r) {
+				} catch Panic(uint c) {
+				} catch (bytes memory d) {
+				} catch {
+				}
+			}
+			}
+		`,
+			valid: true,
+			fn: func(su *SourceUnit, t *testing.T) {
+				body := su.Nodes[0].(ContractDefinition).SubNodes[0].(FunctionDefinition).Block.(Block)
+				s := body.Statements[0].(TryStatement)
+				if _, ok := s.Expression.(FunctionCall).Expression.(FunctionCallOptions); !ok {
+					t.Errorf("unexpected try expression %+v", s.Expression)
+				}
+				expected := []struct {
+					name   string
+					params int
+				}{{"", 2}, {"Error", 1}, {"Panic", 1}, {"", 1}, {"", 0}}
+				if len(s.Clauses) != len(expected) {
+					t.Fatalf("expected %d clauses got %d", len(expected), len(s.Clauses))
+				}
+				for i, exp := range expected {
+					c := s.Clauses[i]
+					if c.ErrorName != exp.name || len(c.Parameters.Paramaters) != exp.params {
+						t.Errorf("clause %d: unexpected %+v", i, c)
+					}
+				}
+				if len(s.Clauses[0].Block.Statements) != 1 {
+					t.Errorf("unexpected success block %+v", s.Clauses[0].Block)
+				}
+			},
+		},
+
+		{
+			name: "try without catch",
+			source: `contract test {
+			function f() { try ext.f() { } }
+			}
+		`,
+			valid: false,
+		},
+
+		{
+			name: "catch without parameters after error name",
+			source: `contract test {
+			function f() { try ext.f() { } catch Error { } }
+			}
+		`,
+			valid: false,
+		},
+
+		{
+			name: "unchecked block",
+			source: `contract test {
+			function f(uint a) returns (uint) {
+				unchecked { a++; }
+				return a;
+			}
+			}
+		`,
+			valid: true,
+			fn: func(su *SourceUnit, t *testing.T) {
+				body := su.Nodes[0].(ContractDefinition).SubNodes[0].(FunctionDefinition).Block.(Block)
+				u, ok := body.Statements[0].(UncheckedBlock)
+				if !ok || len(u.Statements) != 1 {
+					t.Errorf("expected unchecked block got %+v", body.Statements[0])
+				}
+			},
+		},
+
+		{
+			name: "nested unchecked block",
+			source: `contract test {
+			function f() { unchecked { { unchecked { } } } }
+			}
+		`,
+			valid: false,
+		},
+
+		{
+			name: "unchecked block as if body",
+			source: `contract test {
+			function f() { if (a) unchecked { } }
+			}
+		`,
+			valid: false,
+		},
+
+		{
+			name: "modifier",
+			source: `contract test {
+			modifier onlyOwner(address owner) { require(msg.sender == owner); _; }
+			modifier abstractModifier;
+			}
+		`,
+			valid: true,
+			fn: func(su *SourceUnit, t *testing.T) {
+				m := su.Nodes[0].(ContractDefinition).SubNodes[0].(ModifierDefinition)
+				if m.Name != "onlyOwner" || len(m.Paramaters.Paramaters) != 1 {
+					t.Errorf("unexpected modifier %+v", m)
+				}
+				if _, ok := m.Block.(Block).Statements[1].(PlaceholderStatement); !ok {
+					t.Errorf("expected placeholder got %+v", m.Block.(Block).Statements[1])
+				}
+				if m := su.Nodes[0].(ContractDefinition).SubNodes[1].(ModifierDefinition); m.Block != nil {
+					t.Errorf("expected modifier without body got %+v", m)
+				}
+			},
+		},
+
+		{
+			name: "unchecked block in modifier",
+			source: `contract test {
+			modifier m() { unchecked { _; } }
+			}
+		`,
+			valid: false,
+		},
 	}
 
 	for _, tt := range tests {
-		_, err := NewParser(strings.NewReader(tt.source)).Parse()
+		su, err := NewParser(strings.NewReader(tt.source)).Parse()
 		if tt.valid && err != nil {
 			t.Errorf("%s should be valid got: %s\n\n", tt.name, errstring(err))
 		}
 		if !tt.valid && err == nil {
 			t.Errorf("%s should not be valid.  Parsed as valid", tt.name)
 		}
-		// if has fn, call it
+		if tt.fn != nil && err == nil {
+			tt.fn(su, t)
+		}
+		if err == nil {
+			tree, err := ParseSyntaxTree(strings.NewReader(tt.source))
+			if err != nil || tree.Text() != tt.source {
+				t.Errorf("%s should reprint identically, error: %s", tt.name, errstring(err))
+			}
+			formatted, err := Format([]byte(tt.source))
+			if err != nil {
+				t.Errorf("%s should format got: %s", tt.name, err)
+			} else if again, err := Format(formatted); err != nil || !bytes.Equal(again, formatted) {
+				t.Errorf("%s formatting should be idempotent, error: %s\n%s\n%s", tt.name, errstring(err), formatted, again)
+			} else if reparsed, err := NewParser(bytes.NewReader(formatted)).Parse(); err != nil || !reflect.DeepEqual(withoutRaw(su), withoutRaw(reparsed)) {
+				t.Errorf("%s formatting should keep the AST, error: %s\n%s", tt.name, errstring(err), formatted)
+			}
+		}
 	}
 }
 
@@ -113,3 +1153,130 @@ func errstring(err error) string {
 	}
 	return ""
 }
+
+// Ensure standalone yul sources parse into objects.
+func TestParser_ParseYul(t *testing.T) {
+	var tests = []struct {
+		name   string
+		source string
+		valid  bool
+		fn     func(*YulObject, *testing.T)
+	}{
+		{
+			name: "object with sub object and data",
+			source: `object "Token" {
+				code {
+					datacopy(0, dataoffset("Runtime"), datasize("Runtime"))
+					return(0, datasize("Runtime"))
+				}
+				object "Runtime" {
+					code { mstore(0, 1) }
+				}
+				data "prefix" hex"00ff"
+				data "name" "Token"
+			}`,
+			valid: true,
+			fn: func(o *YulObject, t *testing.T) {
+				if o.Name != "Token" || len(o.Code.Statements) != 2 {
+					t.Errorf("unexpected object %+v", o)
+				}
+				if len(o.Objects) != 1 || o.Objects[0].Name != "Runtime" {
+					t.Errorf("unexpected sub objects %+v", o.Objects)
+				}
+				expected := []YulData{{"prefix", "\x00\xff", true}, {"name", "Token", false}}
+				for i, exp := range expected {
+					if i >= len(o.Data) || o.Data[i] != exp {
+						t.Errorf("data %d: expected %+v got %+v", i, exp, o.Data)
+					}
+				}
+			},
+		},
+
+		{
+			name:   "plain block",
+			source: `{ let x := 1 sstore(0, x) }`,
+			valid:  true,
+			fn: func(o *YulObject, t *testing.T) {
+				if o.Name != "object" || len(o.Code.Statements) != 2 {
+					t.Errorf("unexpected object %+v", o)
+				}
+			},
+		},
+
+		{
+			name:   "object without code",
+			source: `object "A" { data "x" "y" }`,
+			valid:  false,
+		},
+
+		{
+			name:   "unknown object member",
+			source: `object "A" { code { } foo "x" }`,
+			valid:  false,
+		},
+
+		{
+			name:   "trailing tokens",
+			source: `{ } { }`,
+			valid:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		o, err := NewParser(strings.NewReader(tt.source)).ParseYul()
+		if tt.valid && err != nil {
+			t.Errorf("%s should be valid got: %s", tt.name, err)
+		} else if !tt.valid && err == nil {
+			t.Errorf("%s should be invalid", tt.name)
+		}
+		if tt.fn != nil && err == nil {
+			tt.fn(o, t)
+		}
+	}
+}
+
+// withoutRaw returns a copy of the tree n with the Raw text of literals
+// cleared, which differs after formatting normalizes the literals
+func withoutRaw(n interface{}) interface{} {
+	return clearRaw(reflect.ValueOf(n)).Interface()
+}
+
+func clearRaw(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Interface, reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type()).Elem()
+		if v.Kind() == reflect.Ptr {
+			c.Set(reflect.New(v.Type().Elem()))
+			c.Elem().Set(clearRaw(v.Elem()))
+		} else {
+			c.Set(clearRaw(v.Elem()))
+		}
+		return c
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(clearRaw(v.Index(i)))
+		}
+		return c
+	case reflect.Struct:
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		if l, ok := c.Addr().Interface().(*Literal); ok {
+			l.Raw = ""
+			return c
+		}
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).PkgPath == "" {
+				c.Field(i).Set(clearRaw(v.Field(i)))
+			}
+		}
+		return c
+	}
+	return v
+}
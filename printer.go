@@ -0,0 +1,1044 @@
+package solparse
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	indentation  = "    "
+	maxLineWidth = 100
+)
+
+// Print writes n as solidity source to w. n can be any node of the AST,
+// including trees constructed by hand, and may be passed by pointer.
+// Operations are parenthesized where their precedence requires it
+func Print(w io.Writer, n Node) error {
+	p := &printer{}
+	p.node(n)
+	if p.err != nil {
+		return p.err
+	}
+	_, err := w.Write(p.buf.Bytes())
+	return err
+}
+
+// printer renders AST nodes as solidity source
+type printer struct {
+	buf    bytes.Buffer
+	indent int
+	err    error
+}
+
+func (p *printer) write(s ...string) {
+	for _, x := range s {
+		p.buf.WriteString(x)
+	}
+}
+
+// newline starts a new line at the current indentation
+func (p *printer) newline() {
+	p.buf.WriteByte('\n')
+	for i := 0; i < p.indent; i++ {
+		p.buf.WriteString(indentation)
+	}
+}
+
+// column returns the width of the current line
+func (p *printer) column() int {
+	b := p.buf.Bytes()
+	return utf8.RuneCount(b[bytes.LastIndexByte(b, '\n')+1:])
+}
+
+// fail records the first node that cannot be printed
+func (p *printer) fail(n interface{}) {
+	if p.err == nil {
+		p.err = fmt.Errorf("Cannot print node of type %T", n)
+	}
+}
+
+// invalid records the first node with invalid contents
+func (p *printer) invalid(n interface{}, reason string) {
+	if p.err == nil {
+		p.err = fmt.Errorf("Cannot print %T: %s", n, reason)
+	}
+}
+
+// deref returns the node a pointer points to
+func deref(n Node) Node {
+	if v := reflect.ValueOf(n); v.Kind() == reflect.Ptr && !v.IsNil() {
+		return v.Elem().Interface()
+	}
+	return n
+}
+
+func (p *printer) sourceUnit(su *SourceUnit) {
+	p.members(su.Nodes)
+	if p.buf.Len() > 0 {
+		p.buf.WriteByte('\n')
+	}
+}
+
+// members prints the definitions of a source unit or contract, separating
+// multi line definitions by blank lines
+func (p *printer) members(nodes []Node) {
+	var prev Node
+	for i, n := range nodes {
+		n = deref(n)
+		if c, ok := n.(Comment); ok && c.Trailing && i > 0 {
+			p.write(" ", c.Text)
+			continue
+		}
+		if i > 0 {
+			if startsGroup(nodes, i) && (isMultiLine(prev) || isMultiLine(nextDefinition(nodes, i))) {
+				p.buf.WriteByte('\n')
+			}
+			p.newline()
+		}
+		p.node(n)
+		if _, ok := n.(VariableDeclaration); ok {
+			p.write(";")
+		}
+		if _, ok := n.(Comment); !ok {
+			prev = n
+		}
+	}
+}
+
+// startsGroup reports whether nodes[i] is not preceded by a comment on its
+// own line, which belongs to the same definition
+func startsGroup(nodes []Node, i int) bool {
+	c, ok := deref(nodes[i-1]).(Comment)
+	return !ok || c.Trailing
+}
+
+func nextDefinition(nodes []Node, i int) Node {
+	for _, n := range nodes[i:] {
+		if _, ok := deref(n).(Comment); !ok {
+			return deref(n)
+		}
+	}
+	return nil
+}
+
+func isMultiLine(n Node) bool {
+	switch n := deref(n).(type) {
+	case ContractDefinition, ModifierDefinition:
+		return true
+	case FunctionDefinition:
+		return n.Block != nil
+	}
+	return false
+}
+
+// statements prints the contents of a block after its opening brace
+func (p *printer) statements(nodes []Node, statement func(Node)) {
+	if len(nodes) == 0 {
+		p.write("}")
+		return
+	}
+	p.indent++
+	for _, n := range nodes {
+		if c, ok := deref(n).(Comment); ok && c.Trailing {
+			p.write(" ", c.Text)
+			continue
+		}
+		p.newline()
+		statement(n)
+	}
+	p.indent--
+	p.newline()
+	p.write("}")
+}
+
+// node prints any node at the current position
+func (p *printer) node(n Node) {
+	switch n := deref(n).(type) {
+	case SourceUnit:
+		p.sourceUnit(&n)
+	case Comment:
+		p.write(n.Text)
+	case ContractDefinition:
+		p.contract(n)
+	case FunctionDefinition:
+		p.function(n)
+	case ModifierDefinition:
+		p.modifier(n)
+	case ModifierInvocation:
+		p.modifierInvocation(n)
+	case VariableDeclaration:
+		p.variableDeclaration(n)
+	case ParameterList:
+		p.parameterList(n, false)
+	case ElementaryTypeName, UserDefinedTypeName, ArrayTypeName:
+		p.typeName(n)
+	case YulBlock, YulVariableDeclaration, YulAssignment, YulIf, YulSwitch, YulForLoop,
+		YulFunctionDefinition, YulBreak, YulContinue, YulLeave, YulExpressionStatement:
+		p.yulStatement(n)
+	case YulFunctionCall, YulIdentifier, YulLiteral:
+		p.yulExpression(n)
+	default:
+		if isStatement(n) {
+			p.statement(n)
+		} else {
+			p.expression(n)
+		}
+	}
+}
+
+func (p *printer) contract(cd ContractDefinition) {
+	if cd.IsLibrary {
+		p.write("library ")
+	} else {
+		p.write("contract ")
+	}
+	p.write(cd.Name)
+	for i, base := range cd.BaseContracts {
+		if i == 0 {
+			p.write(" is ")
+		} else {
+			p.write(", ")
+		}
+		p.write(base.Name)
+	}
+	p.write(" {")
+	if len(cd.SubNodes) == 0 {
+		p.write("}")
+		return
+	}
+	p.indent++
+	if c, ok := deref(cd.SubNodes[0]).(Comment); ok && c.Trailing {
+		p.write(" ", c.Text)
+		cd.SubNodes = cd.SubNodes[1:]
+	}
+	if len(cd.SubNodes) > 0 {
+		p.newline()
+		p.members(cd.SubNodes)
+	}
+	p.indent--
+	p.newline()
+	p.write("}")
+}
+
+func (p *printer) function(f FunctionDefinition) {
+	start := p.buf.Len()
+	column := p.column()
+	p.functionHeader(f, false)
+	if column+p.buf.Len()-start > maxLineWidth && len(f.Paramaters.Paramaters) > 0 {
+		p.buf.Truncate(start)
+		p.functionHeader(f, true)
+	}
+
+	if f.Block == nil {
+		p.write(";")
+		return
+	}
+	p.write(" ")
+	p.statement(f.Block)
+}
+
+func (p *printer) functionHeader(f FunctionDefinition, wrap bool) {
+	switch {
+	case (f.Kind == ConstructorFunction || f.IsConstructor) && f.Name == "":
+		p.write("constructor")
+	case f.Kind == ReceiveFunction:
+		p.write("receive")
+	case f.Kind == FallbackFunction && f.IsLegacyFallback:
+		p.write("function")
+	case f.Kind == FallbackFunction:
+		p.write("fallback")
+	default:
+		if f.Name == "" {
+			p.invalid(f, "missing function name")
+		}
+		p.write("function ", f.Name)
+	}
+	p.parameterList(f.Paramaters, wrap)
+
+	if f.Visibility != "" {
+		p.write(" ", f.Visibility)
+	}
+	switch {
+	case f.IsDeclaredConst:
+		p.write(" constant")
+	case f.IsPayable:
+		p.write(" payable")
+	case f.IsView:
+		p.write(" view")
+	case f.IsPure:
+		p.write(" pure")
+	}
+	for _, m := range f.Modifiers {
+		p.write(" ")
+		p.modifierInvocation(m)
+	}
+	if len(f.ReturnParameters.Paramaters) > 0 {
+		p.write(" returns ")
+		p.parameterList(f.ReturnParameters, false)
+	}
+}
+
+func (p *printer) modifier(m ModifierDefinition) {
+	p.write("modifier ", m.Name)
+	if len(m.Paramaters.Paramaters) > 0 {
+		p.parameterList(m.Paramaters, false)
+	}
+	if m.Block == nil {
+		p.write(";")
+		return
+	}
+	p.write(" ")
+	p.statement(m.Block)
+}
+
+func (p *printer) modifierInvocation(m ModifierInvocation) {
+	p.write(m.Name)
+	if len(m.Arguments) > 0 {
+		p.write("(")
+		p.expressionList(m.Arguments)
+		p.write(")")
+	}
+}
+
+// parameterList prints (a, b), or every parameter on its own line if wrap
+// is set
+func (p *printer) parameterList(pl ParameterList, wrap bool) {
+	p.write("(")
+	if wrap {
+		p.indent++
+	}
+	for i, v := range pl.Paramaters {
+		if wrap {
+			p.newline()
+		} else if i > 0 {
+			p.write(" ")
+		}
+		p.variableDeclaration(v)
+		if i < len(pl.Paramaters)-1 {
+			p.write(",")
+		}
+	}
+	if wrap {
+		p.indent--
+		p.newline()
+	}
+	p.write(")")
+}
+
+func (p *printer) variableDeclaration(v VariableDeclaration) {
+	if v.Type == nil {
+		p.write("var")
+	} else {
+		p.typeName(v.Type)
+	}
+	if v.Visibility != "" {
+		p.write(" ", v.Visibility)
+	}
+	if v.IsDeclaredConst {
+		p.write(" constant")
+	}
+	if v.IsIndexed {
+		p.write(" indexed")
+	}
+	if v.Location != "" {
+		p.write(" ", v.Location)
+	}
+	if v.Identifier != "" {
+		p.write(" ", v.Identifier)
+	}
+	if v.Value != nil {
+		p.write(" = ")
+		p.expression(v.Value)
+	}
+}
+
+func (p *printer) typeName(t TypeName) {
+	switch t := deref(t).(type) {
+	case ElementaryTypeName:
+		p.elementaryTypeName(t)
+	case UserDefinedTypeName:
+		p.write(strings.Join(t.NamePath, "."))
+	case ArrayTypeName:
+		p.typeName(t.BaseType)
+		p.write("[")
+		if t.Length != nil {
+			p.expression(t.Length)
+		}
+		p.write("]")
+	default:
+		p.fail(t)
+	}
+}
+
+func (p *printer) elementaryTypeName(t ElementaryTypeName) {
+	if !IsElementaryTypeName(t.Token) {
+		p.invalid(t, "not an elementary type")
+		return
+	}
+	if t.FirstSize == 0 && (IntM <= t.Token && t.Token <= UFixedMxN) {
+		p.invalid(t, "missing size of "+t.Token.String())
+		return
+	}
+	switch t.Token {
+	case IntM:
+		p.write("int", strconv.Itoa(t.FirstSize))
+	case UIntM:
+		p.write("uint", strconv.Itoa(t.FirstSize))
+	case BytesM:
+		p.write("bytes", strconv.Itoa(t.FirstSize))
+	case FixedMxN:
+		p.write(fmt.Sprintf("fixed%dx%d", t.FirstSize, t.SecondSize))
+	case UFixedMxN:
+		p.write(fmt.Sprintf("ufixed%dx%d", t.FirstSize, t.SecondSize))
+	default:
+		p.write(t.Token.String())
+	}
+}
+
+func isStatement(n Node) bool {
+	switch deref(n).(type) {
+	case Block, UncheckedBlock, PlaceholderStatement, Statement, ReturnStatement, IfStatement,
+		WhileStatement, DoWhileStatement, ForStatement, VariableDeclarationStatement,
+		TryStatement, InlineAssembly:
+		return true
+	}
+	return false
+}
+
+func (p *printer) statement(n Node) {
+	switch s := deref(n).(type) {
+	case Comment:
+		p.write(s.Text)
+	case Block:
+		p.write("{")
+		p.statements(s.Statements, p.statement)
+	case UncheckedBlock:
+		p.write("unchecked {")
+		p.statements(s.Statements, p.statement)
+	case PlaceholderStatement:
+		p.write("_;")
+	case ReturnStatement:
+		p.write("return")
+		if s.Expression != nil {
+			p.write(" ")
+			p.expression(s.Expression)
+		}
+		p.write(";")
+	case IfStatement:
+		p.write("if (")
+		p.expression(s.Condition)
+		p.write(")")
+		trueBody := s.TrueBody
+		if s.FalseBody != nil && endsInIfWithoutElse(trueBody) {
+			// the else would be taken by the inner if
+			trueBody = Block{Statements: []Node{trueBody}}
+		}
+		p.body(trueBody)
+		if s.FalseBody != nil {
+			if _, ok := deref(trueBody).(Block); ok {
+				p.write(" ")
+			} else {
+				p.newline()
+			}
+			p.write("else")
+			if _, ok := deref(s.FalseBody).(IfStatement); ok {
+				p.write(" ")
+				p.statement(s.FalseBody)
+			} else {
+				p.body(s.FalseBody)
+			}
+		}
+	case WhileStatement:
+		p.write("while (")
+		p.expression(s.Condition)
+		p.write(")")
+		p.body(s.Body)
+	case DoWhileStatement:
+		p.write("do")
+		p.body(s.Body)
+		if _, ok := deref(s.Body).(Block); ok {
+			p.write(" ")
+		} else {
+			p.newline()
+		}
+		p.write("while (")
+		p.expression(s.Condition)
+		p.write(");")
+	case ForStatement:
+		p.write("for (")
+		if s.InitializationExpression != nil {
+			p.simpleStatement(s.InitializationExpression)
+		}
+		p.write(";")
+		if s.Condition != nil {
+			p.write(" ")
+			p.expression(s.Condition)
+		}
+		p.write(";")
+		if s.LoopExpression != nil {
+			p.write(" ")
+			p.expression(s.LoopExpression)
+		}
+		p.write(")")
+		p.body(s.Body)
+	case TryStatement:
+		p.tryStatement(s)
+	case InlineAssembly:
+		p.write("assembly ")
+		if s.Dialect != "" {
+			p.write(quote(s.Dialect), " ")
+		}
+		if len(s.Flags) > 0 {
+			p.write("(")
+			for i, f := range s.Flags {
+				if i > 0 {
+					p.write(", ")
+				}
+				p.write(quote(f))
+			}
+			p.write(") ")
+		}
+		p.yulStatement(s.Body)
+	case Statement, VariableDeclarationStatement:
+		p.simpleStatement(s)
+		p.write(";")
+	default:
+		// an expression used as statement
+		p.expression(s)
+		p.write(";")
+	}
+}
+
+// body prints the body of a control flow statement after its header
+func (p *printer) body(n Node) {
+	if _, ok := deref(n).(Block); ok {
+		p.write(" ")
+		p.statement(n)
+		return
+	}
+	p.indent++
+	p.newline()
+	p.statement(n)
+	p.indent--
+}
+
+// endsInIfWithoutElse reports whether an else following n would belong to an
+// if statement inside of n
+func endsInIfWithoutElse(n Node) bool {
+	switch s := deref(n).(type) {
+	case IfStatement:
+		return s.FalseBody == nil || endsInIfWithoutElse(s.FalseBody)
+	case WhileStatement:
+		return endsInIfWithoutElse(s.Body)
+	case ForStatement:
+		return endsInIfWithoutElse(s.Body)
+	}
+	return false
+}
+
+// simpleStatement prints a statement which may appear in the head of a
+// for loop, without the trailing semicolon
+func (p *printer) simpleStatement(n Node) {
+	switch s := deref(n).(type) {
+	case Statement:
+		if s.Expression == nil {
+			if s.Token != Break && s.Token != Continue && s.Token != Throw {
+				p.invalid(s, "missing expression")
+			}
+			p.write(s.Token.String())
+		} else {
+			p.expression(s.Expression)
+		}
+	case VariableDeclarationStatement:
+		p.variableDeclarationStatement(s)
+	default:
+		p.expression(s)
+	}
+}
+
+func (p *printer) variableDeclarationStatement(s VariableDeclarationStatement) {
+	if len(s.Declarations) == 1 && s.Declarations[0] != nil {
+		p.variableDeclaration(*s.Declarations[0])
+	} else {
+		untyped := true
+		for _, d := range s.Declarations {
+			if d != nil && d.Type != nil {
+				untyped = false
+			}
+		}
+		if untyped {
+			// legacy var (a, b)
+			p.write("var ")
+		}
+		p.write("(")
+		for i, d := range s.Declarations {
+			if i > 0 {
+				p.write(", ")
+			}
+			if d == nil {
+				continue
+			}
+			if untyped {
+				p.write(d.Identifier)
+			} else {
+				p.variableDeclaration(*d)
+			}
+		}
+		p.write(")")
+	}
+	if s.InitialValue != nil {
+		p.write(" = ")
+		p.expression(s.InitialValue)
+	}
+}
+
+func (p *printer) tryStatement(s TryStatement) {
+	p.write("try ")
+	p.expression(s.Expression)
+	for i, c := range s.Clauses {
+		if i == 0 {
+			if len(c.Parameters.Paramaters) > 0 {
+				p.write(" returns ")
+				p.parameterList(c.Parameters, false)
+			}
+		} else {
+			p.write(" catch")
+			if c.ErrorName != "" {
+				p.write(" ", c.ErrorName)
+				p.parameterList(c.Parameters, false)
+			} else if len(c.Parameters.Paramaters) > 0 {
+				p.write(" ")
+				p.parameterList(c.Parameters, false)
+			}
+		}
+		p.write(" ")
+		p.statement(c.Block)
+	}
+}
+
+func (p *printer) expressionList(list []Expression) {
+	for i, e := range list {
+		if i > 0 {
+			p.write(", ")
+		}
+		p.expression(e)
+	}
+}
+
+// namedList prints {a: 1, b: 2}
+// namedList prints the named arguments or call options of e
+func (p *printer) namedList(e Expression, names []string, values []Expression) {
+	if len(names) != len(values) {
+		p.invalid(e, "names and values differ in number")
+		return
+	}
+	p.write("{")
+	for i, name := range names {
+		if i > 0 {
+			p.write(", ")
+		}
+		p.write(name, ": ")
+		p.expression(values[i])
+	}
+	p.write("}")
+}
+
+func (p *printer) expression(e Expression) {
+	p.expr(e, 0)
+}
+
+// Precedences of expressions which are not binary operations, parsed
+// trees make parenthesis explicit with tuple expressions
+const (
+	prefixPrecedence  = 100
+	postfixPrecedence = 101
+	primaryPrecedence = 102
+)
+
+func expressionPrecedence(e Expression) int {
+	switch e := deref(e).(type) {
+	case Assignment:
+		return TokenPrecedence(Assign)
+	case ConditionalExpression:
+		return TokenPrecedence(Conditional)
+	case BinaryOperation:
+		return TokenPrecedence(e.Operation)
+	case UnaryOperation:
+		if e.IsPrefix {
+			return prefixPrecedence
+		}
+		return postfixPrecedence
+	case NewExpression:
+		return prefixPrecedence
+	}
+	return primaryPrecedence
+}
+
+// operand prints e, wrapped in parenthesis if it binds weaker than min.
+// parent is the precedence of the binary operation e is an operand of
+func (p *printer) operand(e Expression, min, parent int) {
+	if expressionPrecedence(e) < min {
+		p.write("(")
+		p.expr(e, 0)
+		p.write(")")
+		return
+	}
+	p.expr(e, parent)
+}
+
+// callee prints the base of a call, index or member access
+func (p *printer) callee(e Expression) {
+	if _, ok := deref(e).(NewExpression); ok {
+		p.expr(e, 0)
+		return
+	}
+	p.operand(e, postfixPrecedence, 0)
+}
+
+// startsWithOperator reports whether e is printed starting with a prefix
+// operator sharing its first character with op. Such an operand must be
+// separated from op, a - -b must not become a--b
+func startsWithOperator(e Expression, op Token) bool {
+	switch e := deref(e).(type) {
+	case UnaryOperation:
+		if !e.IsPrefix {
+			return startsWithOperator(e.SubExpression, op)
+		}
+		return e.Token.String()[0] == op.String()[0]
+	case BinaryOperation:
+		return startsWithOperator(e.Expression, op)
+	}
+	return false
+}
+
+// expr prints e, which is an operand of a binary operation with precedence
+// parent or 0 if it is not
+func (p *printer) expr(e Expression, parent int) {
+	switch e := deref(e).(type) {
+	case IdentifierExpression:
+		if e.Literal == "" {
+			p.invalid(e, "missing name")
+		}
+		p.write(e.Literal)
+	case Literal:
+		p.literal(e)
+	case ElementaryTypeName:
+		p.elementaryTypeName(e)
+	case Assignment:
+		p.operand(e.LeftHandSide, TokenPrecedence(Or), 0)
+		p.write(" ", e.AssignmentOperator.String(), " ")
+		p.expression(e.RightHandSide)
+	case ConditionalExpression:
+		p.operand(e.Condition, TokenPrecedence(Or), 0)
+		p.write(" ? ")
+		p.expression(e.TrueExpression)
+		p.write(" : ")
+		p.expression(e.FalseExpression)
+	case BinaryOperation:
+		// operations binding tighter than the surrounding one are printed
+		// without spaces, e.g. a*b + c
+		precedence := TokenPrecedence(e.Operation)
+		separator := " "
+		if parent > 0 && precedence > parent && !startsWithOperator(e.RightHandSide, e.Operation) {
+			separator = ""
+		}
+		// binary operations are left associative
+		p.operand(e.Expression, precedence, precedence)
+		p.write(separator, e.Operation.String(), separator)
+		p.operand(e.RightHandSide, precedence+1, precedence)
+	case UnaryOperation:
+		if !e.IsPrefix {
+			if sub, ok := deref(e.SubExpression).(UnaryOperation); ok && !sub.IsPrefix {
+				// a++ is not assignable, neither a++++ nor (a++)++ is valid
+				p.invalid(e, "postfix operator applied to a postfix operation")
+			}
+			p.operand(e.SubExpression, postfixPrecedence, 0)
+			p.write(e.Token.String())
+			return
+		}
+		p.write(e.Token.String())
+		if e.Token == Delete {
+			p.write(" ")
+		} else if sub, ok := deref(e.SubExpression).(UnaryOperation); ok && sub.IsPrefix &&
+			e.Token.String()[0] == sub.Token.String()[0] {
+			// keep - -x from turning into --x
+			p.write(" ")
+		}
+		p.operand(e.SubExpression, prefixPrecedence, 0)
+	case TupleExpression:
+		if e.IsInlineArray {
+			p.write("[")
+		} else {
+			p.write("(")
+		}
+		for i, c := range e.Components {
+			if i > 0 {
+				p.write(", ")
+			}
+			if c != nil {
+				p.expression(c)
+			}
+		}
+		if e.IsInlineArray {
+			p.write("]")
+		} else {
+			p.write(")")
+		}
+	case MemberAccess:
+		p.callee(e.Expression)
+		p.write(".", e.MemberName)
+	case IndexAccess:
+		p.callee(e.BaseExpression)
+		p.write("[")
+		if e.IndexExpression != nil {
+			p.expression(e.IndexExpression)
+		}
+		p.write("]")
+	case IndexRangeAccess:
+		p.callee(e.BaseExpression)
+		p.write("[")
+		if e.StartExpression != nil {
+			p.expression(e.StartExpression)
+		}
+		p.write(":")
+		if e.EndExpression != nil {
+			p.expression(e.EndExpression)
+		}
+		p.write("]")
+	case FunctionCall:
+		p.callee(e.Expression)
+		p.write("(")
+		if e.Names != nil {
+			p.namedList(e, e.Names, e.Arguments)
+		} else {
+			p.expressionList(e.Arguments)
+		}
+		p.write(")")
+	case FunctionCallOptions:
+		p.callee(e.Expression)
+		p.namedList(e, e.Names, e.Options)
+	case NewExpression:
+		p.write("new ")
+		p.typeName(e.TypeName)
+	default:
+		p.fail(e)
+	}
+}
+
+func (p *printer) literal(l Literal) {
+	if l.Value == "" && (l.Kind == BoolLiteralKind || l.Kind == NumberLiteralKind) {
+		p.invalid(l, "missing value")
+	}
+	switch l.Kind {
+	case StringLiteralKind:
+		p.write(quote(l.Value))
+	case UnicodeStringLiteralKind:
+		p.write("unicode", quoteUnicode(l.Value))
+	case HexStringLiteralKind:
+		p.write("hex\"", hex.EncodeToString([]byte(l.Value)), "\"")
+	default:
+		p.write(l.Value)
+	}
+	if l.SubDenomination != EOS {
+		p.write(" ", l.SubDenomination.String())
+	}
+}
+
+// quote returns s as a string literal, escaping everything but printable
+// ASCII characters
+func quote(s string) string {
+	var buf bytes.Buffer
+	buf.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		writeEscapedByte(&buf, s[i])
+	}
+	buf.WriteByte('"')
+	return buf.String()
+}
+
+// quoteUnicode is quote for unicode literals, which may contain printable
+// non ASCII characters as is
+func quoteUnicode(s string) string {
+	var buf bytes.Buffer
+	buf.WriteByte('"')
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r >= utf8.RuneSelf && r != utf8.RuneError {
+			buf.WriteString(s[i : i+size])
+		} else {
+			for j := i; j < i+size; j++ {
+				writeEscapedByte(&buf, s[j])
+			}
+		}
+		i += size
+	}
+	buf.WriteByte('"')
+	return buf.String()
+}
+
+func writeEscapedByte(buf *bytes.Buffer, c byte) {
+	switch c {
+	case '"', '\\':
+		buf.WriteByte('\\')
+		buf.WriteByte(c)
+	case '\n':
+		buf.WriteString("\\n")
+	case '\r':
+		buf.WriteString("\\r")
+	case '\t':
+		buf.WriteString("\\t")
+	default:
+		if c < 0x20 || c >= 0x7f {
+			fmt.Fprintf(buf, "\\x%02x", c)
+		} else {
+			buf.WriteByte(c)
+		}
+	}
+}
+
+func (p *printer) yulStatement(n Node) {
+	switch s := deref(n).(type) {
+	case Comment:
+		p.write(s.Text)
+	case YulBlock:
+		p.write("{")
+		p.statements(s.Statements, p.yulStatement)
+	case YulVariableDeclaration:
+		p.write("let ")
+		p.yulTypedNames(s.Variables)
+		if s.Value != nil {
+			p.write(" := ")
+			p.yulExpression(s.Value)
+		}
+	case YulAssignment:
+		for i, v := range s.VariableNames {
+			if i > 0 {
+				p.write(", ")
+			}
+			p.write(v.Name)
+		}
+		p.write(" := ")
+		p.yulExpression(s.Value)
+	case YulIf:
+		p.write("if ")
+		p.yulExpression(s.Condition)
+		p.write(" ")
+		p.yulStatement(s.Body)
+	case YulSwitch:
+		p.write("switch ")
+		p.yulExpression(s.Expression)
+		for _, c := range s.Cases {
+			p.newline()
+			if c.Value == nil {
+				p.write("default ")
+			} else {
+				p.write("case ")
+				p.yulExpression(*c.Value)
+				p.write(" ")
+			}
+			p.yulStatement(c.Body)
+		}
+	case YulForLoop:
+		p.write("for ")
+		p.yulInlineBlock(s.Pre)
+		p.write(" ")
+		p.yulExpression(s.Condition)
+		p.write(" ")
+		p.yulInlineBlock(s.Post)
+		p.write(" ")
+		p.yulStatement(s.Body)
+	case YulFunctionDefinition:
+		p.write("function ", s.Name, "(")
+		p.yulTypedNames(s.Parameters)
+		p.write(")")
+		if len(s.ReturnVariables) > 0 {
+			p.write(" -> ")
+			p.yulTypedNames(s.ReturnVariables)
+		}
+		p.write(" ")
+		p.yulStatement(s.Body)
+	case YulBreak:
+		p.write("break")
+	case YulContinue:
+		p.write("continue")
+	case YulLeave:
+		p.write("leave")
+	case YulExpressionStatement:
+		p.yulExpression(s.Expression)
+	default:
+		p.fail(n)
+	}
+}
+
+// yulInlineBlock prints the init and post blocks of a for loop on a single
+// line if they only contain simple statements
+func (p *printer) yulInlineBlock(b YulBlock) {
+	for _, s := range b.Statements {
+		switch deref(s).(type) {
+		case YulVariableDeclaration, YulAssignment, YulExpressionStatement:
+		default:
+			p.yulStatement(b)
+			return
+		}
+	}
+	if len(b.Statements) == 0 {
+		p.write("{ }")
+		return
+	}
+	p.write("{ ")
+	for i, s := range b.Statements {
+		if i > 0 {
+			p.write(" ")
+		}
+		p.yulStatement(s)
+	}
+	p.write(" }")
+}
+
+func (p *printer) yulTypedNames(names []YulTypedName) {
+	for i, n := range names {
+		if i > 0 {
+			p.write(", ")
+		}
+		p.write(n.Name)
+		if n.Type != "" {
+			p.write(":", n.Type)
+		}
+	}
+}
+
+func (p *printer) yulExpression(e Expression) {
+	switch e := deref(e).(type) {
+	case YulFunctionCall:
+		p.write(e.FunctionName, "(")
+		for i, arg := range e.Arguments {
+			if i > 0 {
+				p.write(", ")
+			}
+			p.yulExpression(arg)
+		}
+		p.write(")")
+	case YulIdentifier:
+		p.write(e.Name)
+	case YulLiteral:
+		switch e.Kind {
+		case StringLiteralKind:
+			p.write(quote(e.Value))
+		case HexStringLiteralKind:
+			p.write("hex\"", hex.EncodeToString([]byte(e.Value)), "\"")
+		default:
+			p.write(e.Value)
+		}
+		if e.Type != "" {
+			p.write(":", e.Type)
+		}
+	default:
+		p.fail(e)
+	}
+}
@@ -0,0 +1,147 @@
+package solparse
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Ensure hand built nodes are printed as valid source.
+func TestPrint(t *testing.T) {
+	a, b, c := IdentifierExpression{"a"}, IdentifierExpression{"b"}, IdentifierExpression{"c"}
+	uint256 := ElementaryTypeName{Token: UIntM, FirstSize: 256}
+
+	var tests = []struct {
+		name string
+		node Node
+		exp  string
+		err  string
+	}{
+		{name: "sized type", node: uint256, exp: "uint256"},
+		{name: "pointer", node: &MemberAccess{Expression: a, MemberName: "x"}, exp: "a.x"},
+		{
+			name: "left operand",
+			node: BinaryOperation{BinaryOperation{a, Add, b}, Mul, c},
+			exp:  "(a + b) * c",
+		},
+		{
+			name: "right operand",
+			node: BinaryOperation{a, Sub, BinaryOperation{b, Sub, c}},
+			exp:  "a - (b - c)",
+		},
+		{
+			name: "tighter operand",
+			node: BinaryOperation{BinaryOperation{a, Mul, b}, Add, c},
+			exp:  "a*b + c",
+		},
+		{
+			name: "unary operand",
+			node: UnaryOperation{Token: Sub, SubExpression: BinaryOperation{a, Add, b}, IsPrefix: true},
+			exp:  "-(a + b)",
+		},
+		{
+			name: "member base",
+			node: MemberAccess{Expression: BinaryOperation{a, Add, b}, MemberName: "x"},
+			exp:  "(a + b).x",
+		},
+		{
+			name: "conditional condition",
+			node: ConditionalExpression{Assignment{a, Assign, b}, b, c},
+			exp:  "(a = b) ? b : c",
+		},
+		{
+			name: "state variable",
+			node: &ContractDefinition{Name: "C", SubNodes: []Node{
+				VariableDeclaration{Type: uint256, Identifier: "x", Visibility: "public"},
+				FunctionDefinition{
+					Name:       "set",
+					Visibility: "external",
+					Paramaters: ParameterList{[]VariableDeclaration{{Type: uint256, Identifier: "v"}}},
+					Block: &Block{[]Node{
+						Statement{Expression: Assignment{IdentifierExpression{"x"}, Assign, IdentifierExpression{"v"}}},
+					}},
+				},
+			}},
+			exp: `contract C {
+    uint256 public x;
+
+    function set(uint256 v) external {
+        x = v;
+    }
+}`,
+		},
+
+		{name: "unknown node", node: 1, err: "Cannot print node of type int"},
+		{name: "missing size", node: ElementaryTypeName{Token: UIntM}, err: "Cannot print solparse.ElementaryTypeName: missing size of uintM"},
+		{name: "missing name", node: IdentifierExpression{}, err: "Cannot print solparse.IdentifierExpression: missing name"},
+		{name: "missing expression", node: Statement{}, err: "Cannot print solparse.Statement: missing expression"},
+		{
+			name: "postfix of postfix",
+			node: UnaryOperation{Token: Inc, SubExpression: UnaryOperation{Token: Inc, SubExpression: a}},
+			err:  "Cannot print solparse.UnaryOperation: postfix operator applied to a postfix operation",
+		},
+		{
+			name: "missing argument",
+			node: FunctionCall{Expression: IdentifierExpression{"f"}, Arguments: []Expression{b}, Names: []string{"a", "b"}},
+			err:  "Cannot print solparse.FunctionCall: names and values differ in number",
+		},
+		{
+			name: "missing option",
+			node: FunctionCallOptions{Expression: IdentifierExpression{"f"}, Names: []string{"value"}},
+			err:  "Cannot print solparse.FunctionCallOptions: names and values differ in number",
+		},
+	}
+
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		err := Print(&buf, tt.node)
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("%s: expected error %q, got %v", tt.name, tt.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", tt.name, err)
+			continue
+		}
+		if got := strings.TrimSpace(buf.String()); got != tt.exp {
+			t.Errorf("%s:\nexpected:\n%s\ngot:\n%s", tt.name, tt.exp, got)
+			continue
+		}
+		if _, ok := tt.node.(*ContractDefinition); ok {
+			if _, err := NewParser(strings.NewReader(buf.String())).Parse(); err != nil {
+				t.Errorf("%s: printed source does not parse: %s", tt.name, err)
+			}
+		}
+	}
+}
+
+// Ensure an else is not taken by an inner if when the tree is reparsed.
+func TestPrint_DanglingElse(t *testing.T) {
+	x := Statement{Expression: IdentifierExpression{"x"}}
+	y := Statement{Expression: IdentifierExpression{"y"}}
+	inner := IfStatement{Condition: IdentifierExpression{"b"}, TrueBody: x}
+	for _, body := range []Node{inner, WhileStatement{Condition: IdentifierExpression{"c"}, Body: inner}} {
+		outer := IfStatement{Condition: IdentifierExpression{"a"}, TrueBody: body, FalseBody: y}
+		fd := FunctionDefinition{Name: "f", Block: Block{[]Node{outer}}}
+		cd := ContractDefinition{Name: "C", SubNodes: []Node{fd}}
+
+		var buf bytes.Buffer
+		if err := Print(&buf, cd); err != nil {
+			t.Fatal(err)
+		}
+		su, err := NewParser(&buf).Parse()
+		if err != nil {
+			t.Fatalf("printed source does not parse: %s", err)
+		}
+
+		// the printer wraps the true branch in a block
+		outer.TrueBody = Block{[]Node{body}}
+		got := su.Nodes[0].(ContractDefinition).SubNodes[0].(FunctionDefinition).Block.(Block).Statements[0]
+		if !reflect.DeepEqual(got, outer) {
+			t.Errorf("expected %+v got %+v", outer, got)
+		}
+	}
+}
@@ -4,22 +4,35 @@ import (
 	"bufio"
 	"bytes"
 	"io"
+	"strings"
+	"unicode/utf8"
 )
 
 // Solidity scanner
 
 type Scanner struct {
-	r       *bufio.Reader
-	curTok  *TokenDesc
-	nextTok *TokenDesc
-	char    rune // 1 character look-ahead
+	r           *bufio.Reader
+	curTok      *TokenDesc
+	nextTok     *TokenDesc
+	nextNextTok *TokenDesc
+	char        rune         // 1 character look-ahead
+	pos         int          // byte offset of char
+	readPos     int          // byte offset of the next character to read
+	raw         bytes.Buffer // source text of the token being scanned
+
+	// trivia mode only
+	keepTrivia bool
+	src        bytes.Buffer // source read so far
+	triviaEnd  int          // end of the trivia already handed out
+	comments   []Span       // comments not yet taken by the parser
 }
 
 type TokenDesc struct {
-	token    Token
-	location SourceLocation
-	lit      LiteralScope
-	info     ExtendedTokenInfo
+	token Token
+	span  Span
+	lit   LiteralScope
+	info  ExtendedTokenInfo
+	raw   string // source text of string literals
 }
 
 type LiteralScope struct {
@@ -28,10 +41,10 @@ type LiteralScope struct {
 	buf      bytes.Buffer
 }
 
-type SourceLocation struct {
-	start      int
-	end        int
-	sourceName string
+// Span is the byte range [Start, End) of a token in the source
+type Span struct {
+	Start int
+	End   int
 }
 
 type ExtendedTokenInfo struct {
@@ -45,42 +58,133 @@ func NewScanner(r io.Reader) *Scanner {
 	return s
 }
 
+// NewTriviaScanner returns a scanner that keeps the source text so whitespace
+// and comments can be returned as trivia by ScanWithTrivia
+func NewTriviaScanner(r io.Reader) *Scanner {
+	s := &Scanner{keepTrivia: true}
+	s.r = bufio.NewReader(io.TeeReader(r, &s.src))
+	s.reset()
+	return s
+}
+
 func (s *Scanner) advance() rune {
-	var err error
-	s.char, _, err = s.r.ReadRune()
+	if s.readPos > 0 && s.char != eof {
+		s.raw.WriteRune(s.char)
+	}
+	s.pos = s.readPos
+	c, size, err := s.r.ReadRune()
 	if err != nil {
 		s.char = eof
 		return eof
 	}
+	s.char = c
+	s.readPos += size
 	return s.char
 }
 
+// Scan returns the current token, its position and its literal and advances
+// to the next token. The literal is the identifier or keyword as written, the
+// decoded contents of string literals, the text of numbers and empty for
+// operators. Once the end of the source is reached Scan keeps returning EOS
+func (s *Scanner) Scan() (tok Token, span Span, lit string) {
+	tok, span, lit = s.curTok.token, s.curTok.span, s.currentLiteral()
+	s.next()
+	return
+}
+
 func (s *Scanner) next() Token {
 	s.curTok = s.nextTok
+	s.nextTok = s.nextNextTok
 	s.scanToken()
 	return s.curTok.token
 }
 
+// peekChar returns the character after the current one without consuming it
+func (s *Scanner) peekChar() rune {
+	c, _, err := s.r.ReadRune()
+	if err != nil {
+		return eof
+	}
+	_ = s.r.UnreadRune()
+	return c
+}
+
 func (s *Scanner) selectToken(then Token) Token {
 	s.advance()
 	return then
 }
 
+// selectTokenOrAssign consumes the current character and returns assign if it
+// is followed by '=', otherwise tok
+func (s *Scanner) selectTokenOrAssign(tok, assign Token) Token {
+	s.advance()
+	if s.char == '=' {
+		return s.selectToken(assign)
+	}
+	return tok
+}
+
+func (s *Scanner) skipSingleLineComment() Token {
+	for s.char != eof && !isLineTerminator(s.char) {
+		s.advance()
+	}
+	return Whitespace
+}
+
+func (s *Scanner) skipMultiLineComment() Token {
+	for s.char != eof {
+		c := s.char
+		s.advance()
+		if c == '*' && s.char == '/' {
+			s.advance()
+			return Whitespace
+		}
+	}
+	// unterminated multi-line comment
+	return Illegal
+}
+
 func (s *Scanner) scanToken() {
-	s.nextTok = &TokenDesc{}
+	s.nextNextTok = &TokenDesc{}
 
 	var m, n int
 	var tok Token
+	var start int
 	for {
+		start = s.pos
+		s.raw.Reset()
 		switch s.char {
-		case '\n', ' ', '\t':
+		case '\n', '\r', ' ', '\t':
 			tok = s.selectToken(Whitespace)
 		case '"', '\'':
-			tok = s.scanString()
+			tok = s.scanString(false)
 		case '<':
-			panic("handle <")
+			// < <= << <<=
+			s.advance()
+			if s.char == '=' {
+				tok = s.selectToken(LessThanOrEqual)
+			} else if s.char == '<' {
+				tok = s.selectTokenOrAssign(SHL, AssignShl)
+			} else {
+				tok = LessThan
+			}
 		case '>':
-			panic("handle >")
+			// > >= >> >>= >>> >>>=
+			s.advance()
+			if s.char == '=' {
+				tok = s.selectToken(GreaterThanOrEqual)
+			} else if s.char == '>' {
+				s.advance()
+				if s.char == '>' {
+					tok = s.selectTokenOrAssign(SHR, AsignShr)
+				} else if s.char == '=' {
+					tok = s.selectToken(AssignSar)
+				} else {
+					tok = SAR
+				}
+			} else {
+				tok = GreaterThan
+			}
 		case '=':
 			s.advance()
 			if s.char == '=' {
@@ -88,10 +192,15 @@ func (s *Scanner) scanToken() {
 			} else if s.char == '>' {
 				tok = s.selectToken(Arrow)
 			} else {
-				tok = s.selectToken(Assign)
+				tok = Assign
 			}
 		case '!':
-			panic("handle !")
+			s.advance()
+			if s.char == '=' {
+				tok = s.selectToken(NotEqual)
+			} else {
+				tok = Not
+			}
 		case '+':
 			s.advance()
 			if s.char == '+' {
@@ -99,10 +208,20 @@ func (s *Scanner) scanToken() {
 			} else if s.char == '=' {
 				tok = s.selectToken(AssignAdd)
 			} else {
-				tok = s.selectToken(Add)
+				tok = Add
 			}
 		case '-':
-			panic("handle -")
+			// - -- -= ->
+			s.advance()
+			if s.char == '-' {
+				tok = s.selectToken(Dec)
+			} else if s.char == '>' {
+				tok = s.selectToken(RightArrow)
+			} else if s.char == '=' {
+				tok = s.selectToken(AssignSub)
+			} else {
+				tok = Sub
+			}
 		case '*':
 			s.advance()
 			if s.char == '*' {
@@ -110,22 +229,57 @@ func (s *Scanner) scanToken() {
 			} else if s.char == '=' {
 				tok = s.selectToken(AssignMul)
 			} else {
-				tok = s.selectToken(Mul)
+				tok = Mul
 			}
 		case '%':
-			panic("handle mod")
+			tok = s.selectTokenOrAssign(Mod, AssignMod)
 		case '/':
-			panic("handle /")
+			// /  // /* /=
+			s.advance()
+			if s.char == '/' {
+				tok = s.skipSingleLineComment()
+			} else if s.char == '*' {
+				s.advance()
+				tok = s.skipMultiLineComment()
+			} else if s.char == '=' {
+				tok = s.selectToken(AssignDiv)
+			} else {
+				tok = Div
+			}
+			if tok == Whitespace && s.keepTrivia {
+				s.comments = append(s.comments, Span{Start: start, End: s.pos})
+			}
 		case '&':
-			panic("handle &")
+			s.advance()
+			if s.char == '&' {
+				tok = s.selectToken(And)
+			} else if s.char == '=' {
+				tok = s.selectToken(AssignBitAnd)
+			} else {
+				tok = BitAnd
+			}
 		case '|':
-			panic("handle |")
+			s.advance()
+			if s.char == '|' {
+				tok = s.selectToken(Or)
+			} else if s.char == '=' {
+				tok = s.selectToken(AssignBitOr)
+			} else {
+				tok = BitOr
+			}
 		case '^':
-			panic("handle ^")
+			tok = s.selectTokenOrAssign(BitXor, AssignBitXor)
 		case '.':
-			panic("handle number")
+			// . Number
+			s.advance()
+			if isDecimalDigit(s.char) {
+				tok = s.scanNumber('.')
+			} else {
+				tok = Period
+			}
 		case ':':
-			tok = s.selectToken(Colon)
+			// : :=
+			tok = s.selectTokenOrAssign(Colon, AssemblyAssign)
 		case ';':
 			tok = s.selectToken(Semicolon)
 		case ',':
@@ -149,12 +303,14 @@ func (s *Scanner) scanToken() {
 		default:
 			if isIdentifierStart(s.char) {
 				tok, m, n = s.scanIdentifierOrKeyword()
-				if tok == Hex {
+				if tok == Hex || tok == Unicode {
 					m, n = 0, 0
-					if s.char == '"' || s.char == '\'' {
+					if s.char != '"' && s.char != '\'' {
+						tok = Illegal
+					} else if tok == Hex {
 						tok = s.scanHexString()
 					} else {
-						tok = Illegal
+						tok = s.scanString(true)
 					}
 				}
 			} else if isDecimalDigit(s.char) {
@@ -171,7 +327,11 @@ func (s *Scanner) scanToken() {
 		}
 	}
 	info := ExtendedTokenInfo{firstSize: m, secondSize: n}
-	s.nextTok = &TokenDesc{token: tok, info: info, lit: s.nextTok.lit}
+	span := Span{Start: start, End: s.pos}
+	s.nextNextTok = &TokenDesc{token: tok, span: span, info: info, lit: s.nextNextTok.lit}
+	if tok == StringLiteral || tok == HexStringLiteral || tok == UnicodeStringLiteral {
+		s.nextNextTok.raw = s.raw.String()
+	}
 }
 
 func (s *Scanner) scanIdentifierOrKeyword() (Token, int, int) {
@@ -182,7 +342,7 @@ func (s *Scanner) scanIdentifierOrKeyword() (Token, int, int) {
 	for isIdentifierPart(s.char) {
 		s.addLiteralCharAndAdvance()
 	}
-	return tokenFromIdentifierOrKeyword(s.nextTok.lit.String())
+	return tokenFromIdentifierOrKeyword(s.nextNextTok.lit.String())
 }
 
 func (s *Scanner) addLiteralCharAndAdvance() {
@@ -190,14 +350,23 @@ func (s *Scanner) addLiteralCharAndAdvance() {
 	s.advance()
 }
 
+// scanDecimalDigits scans digits and the underscores grouping them. The
+// underscores are validated once the whole number has been scanned
 func (s *Scanner) scanDecimalDigits() {
-	for isDecimalDigit(s.char) {
+	if !isDecimalDigit(s.char) {
+		return
+	}
+	for isDecimalDigit(s.char) || s.char == '_' {
 		s.addLiteralCharAndAdvance()
 	}
 }
 
 func (s *Scanner) addLiteralChar(c rune) {
-	s.nextTok.lit.buf.WriteRune(c)
+	s.nextNextTok.lit.buf.WriteRune(c)
+}
+
+func (s *Scanner) addLiteralByte(b byte) {
+	s.nextNextTok.lit.buf.WriteByte(b)
 }
 
 func (s *Scanner) currentToken() Token {
@@ -208,10 +377,23 @@ func (s *Scanner) currentLiteral() string {
 	return s.curTok.lit.String()
 }
 
+// currentRaw returns the source text of the current string literal
+func (s *Scanner) currentRaw() string {
+	return s.curTok.raw
+}
+
+func (s *Scanner) currentTokenInfo() (int, int) {
+	return s.curTok.info.firstSize, s.curTok.info.secondSize
+}
+
 func (s *Scanner) peekNextToken() Token {
 	return s.nextTok.token
 }
 
+func (s *Scanner) peekNextNextToken() Token {
+	return s.nextNextTok.token
+}
+
 func (l LiteralScope) String() string {
 	return l.buf.String()
 }
@@ -226,9 +408,10 @@ const (
 
 func (s *Scanner) scanNumber(charSeen rune) (tok Token) {
 	kind := DecimalKind
-	s.nextTok.lit = LiteralScope{}
+	s.nextNextTok.lit = LiteralScope{}
 
 	if charSeen == '.' {
+		// the period has already been consumed
 		s.addLiteralChar('.')
 		s.scanDecimalDigits()
 	} else {
@@ -240,15 +423,19 @@ func (s *Scanner) scanNumber(charSeen rune) (tok Token) {
 				if !isHexDigit(s.char) {
 					return Illegal
 				}
-				for isHexDigit(s.char) {
+				for isHexDigit(s.char) || s.char == '_' {
 					s.addLiteralCharAndAdvance()
 				}
+			} else if isDecimalDigit(s.char) {
+				// octal numbers are not allowed
+				return Illegal
 			}
 		}
 
 		if kind == DecimalKind {
 			s.scanDecimalDigits()
-			if s.char == '.' {
+			// a period not followed by a digit is a member access, e.g. 1.foo
+			if s.char == '.' && isDecimalDigit(s.peekChar()) {
 				s.addLiteralCharAndAdvance()
 				s.scanDecimalDigits()
 			}
@@ -275,21 +462,52 @@ func (s *Scanner) scanNumber(charSeen rune) (tok Token) {
 		return Illegal
 	}
 
+	if !validNumberUnderscores(s.nextNextTok.lit.String(), kind) {
+		return Illegal
+	}
+
 	return Number
 }
 
-func (s *Scanner) scanString() Token {
+// validNumberUnderscores reports whether every underscore in a number
+// literal separates two digits, e.g. 1_000_000 or 0xff_ff
+func validNumberUnderscores(lit string, kind NumberKind) bool {
+	if strings.HasSuffix(lit, "_") || strings.Contains(lit, "__") {
+		return false
+	}
+	invalid := []string{"x_", "X_"}
+	if kind == DecimalKind {
+		invalid = []string{"._", "_.", "_e", "e_", "_E", "E_", "-_", "+_"}
+	}
+	for _, seq := range invalid {
+		if strings.Contains(lit, seq) {
+			return false
+		}
+	}
+	return true
+}
+
+// scanString scans a quoted string literal. Like solc only printable ASCII
+// is allowed unless it is the body of a unicode"..." literal
+func (s *Scanner) scanString(isUnicode bool) Token {
 	quote := s.char
-	s.nextTok.lit = LiteralScope{}
+	s.nextNextTok.lit = LiteralScope{}
 	s.advance() // consume quote
 	for s.char != quote && s.char != eof && !isLineTerminator(s.char) {
 		c := s.char
 		s.advance()
 		if c == '\\' {
 			if !s.scanEscape() {
-				return Illegal
+				return s.illegalString(quote, isUnicode)
 			}
 		} else {
+			if isUnicode && c == utf8.RuneError {
+				// invalid UTF-8 in the source
+				return s.skipLiteral(quote)
+			}
+			if !isUnicode && (c <= 0x1f || c >= 0x7f) {
+				return Illegal
+			}
 			s.addLiteralChar(c)
 		}
 	}
@@ -297,9 +515,21 @@ func (s *Scanner) scanString() Token {
 		return Illegal
 	}
 	s.advance()
+	if isUnicode {
+		return UnicodeStringLiteral
+	}
 	return StringLiteral
 }
 
+// illegalString ends an illegal string literal. Like solc regular strings end
+// at the illegal character, unicode literals are skipped as a whole
+func (s *Scanner) illegalString(quote rune, isUnicode bool) Token {
+	if isUnicode {
+		return s.skipLiteral(quote)
+	}
+	return Illegal
+}
+
 func (s *Scanner) scanEscape() bool {
 	c := s.char
 	s.advance()
@@ -322,17 +552,20 @@ func (s *Scanner) scanEscape() bool {
 	case 'v':
 		c = '\v'
 	case 'u':
-		panic("unicode not yet implemted")
-		//var codepoint rune
-		//if !s.scanUnicode(&codepoint) {
-		//return false
-		//}
-		//s.buf.WriteRune(codepoint)
+		// \uXXXX is added UTF-8 encoded
+		if !s.scanUnicode(&c) {
+			return false
+		}
 	case 'x':
-		var ok bool
-		if c, ok = s.scanHexByte(); !ok {
+		// \xXX is a raw byte
+		b, ok := s.scanHexDigits(2)
+		if !ok {
 			return false
 		}
+		s.addLiteralByte(byte(b))
+		return true
+	default:
+		return false
 	}
 
 	s.addLiteralChar(c)
@@ -340,20 +573,20 @@ func (s *Scanner) scanEscape() bool {
 }
 
 func (s *Scanner) scanUnicode(cp *rune) bool {
-	panic("unicode not yet implemented")
-}
-
-func (s *Scanner) scanHexString() Token {
-	panic("hex string not yet implemented")
+	x, ok := s.scanHexDigits(4)
+	*cp = x
+	return ok
 }
 
-func (s *Scanner) scanHexByte() (rune, bool) {
+// scanHexDigits consumes n hex digits and returns their value. It stops at
+// the first character which is not a hex digit, leaving the illegal escape
+// ending there
+func (s *Scanner) scanHexDigits(n int) (rune, bool) {
 	x := 0
-	for i := 0; i < 2; i++ {
+	for i := 0; i < n; i++ {
 		d := hexValue(s.char)
 		if d < 0 {
-			s.rollback(i)
-			return rune(0), false
+			return rune(x), false
 		}
 		x = x*16 + d
 		s.advance()
@@ -361,6 +594,61 @@ func (s *Scanner) scanHexByte() (rune, bool) {
 	return rune(x), true
 }
 
+// scanHexString scans the quoted part of hex"..." and decodes it to raw
+// bytes. Pairs of hex digits may be separated by a single underscore
+func (s *Scanner) scanHexString() Token {
+	quote := s.char
+	s.nextNextTok.lit = LiteralScope{}
+	s.advance() // consume quote
+	allowUnderscore := false
+	for s.char != quote && s.char != eof {
+		if b, ok := s.scanHexByte(); ok {
+			s.addLiteralByte(byte(b))
+			allowUnderscore = true
+		} else if s.char == '_' {
+			s.advance()
+			if !allowUnderscore || s.char == quote {
+				return s.skipLiteral(quote)
+			}
+			allowUnderscore = false
+		} else {
+			return s.skipLiteral(quote)
+		}
+	}
+	if s.char != quote {
+		return Illegal
+	}
+	s.advance()
+	return HexStringLiteral
+}
+
+// scanHexByte consumes two hex digits. Nothing is consumed if they are not
+func (s *Scanner) scanHexByte() (rune, bool) {
+	hi, lo := hexValue(s.char), hexValue(s.peekChar())
+	if hi < 0 || lo < 0 {
+		return rune(0), false
+	}
+	s.advance()
+	s.advance()
+	return rune(hi*16 + lo), true
+}
+
+// skipLiteral consumes the rest of an illegal quoted literal, so that the
+// whole literal is a single Illegal token and scanning resumes after it
+func (s *Scanner) skipLiteral(quote rune) Token {
+	for s.char != quote && s.char != eof && !isLineTerminator(s.char) {
+		c := s.char
+		s.advance()
+		if c == '\\' && s.char != eof {
+			s.advance()
+		}
+	}
+	if s.char == quote {
+		s.advance()
+	}
+	return Illegal
+}
+
 func (s *Scanner) skipWhitespace() bool {
 	ret := false
 	for isWhitespace(s.char) {
@@ -371,31 +659,14 @@ func (s *Scanner) skipWhitespace() bool {
 }
 
 func (s *Scanner) reset() {
-	// source.reset()
-	var err error
-	s.char, _, err = s.r.ReadRune()
-	if err != nil {
-		s.char = eof
-	}
+	s.advance()
 	s.skipWhitespace()
 	s.scanToken()
+	s.nextTok = s.nextNextTok
+	s.scanToken()
 	s.next()
 }
 
-func (s *Scanner) rollback(i int) {
-	if i <= 0 {
-		return
-	}
-	for x := 0; x < i+1; x++ {
-		_ = s.r.UnreadRune()
-	}
-	var err error
-	s.char, _, _ = s.r.ReadRune()
-	if err != nil {
-		s.char = eof
-	}
-}
-
 // eof represents a marker rune for the end of the reader.
 var eof = rune(-1)
 
@@ -405,15 +676,19 @@ func isIdentifierStart(ch rune) bool {
 func isHexDigit(ch rune) bool {
 	return isDecimalDigit(ch) || ('a' <= ch && ch <= 'f') || ('A' <= ch && ch <= 'F')
 }
-func isAssignmentOp(tok Token) bool { return Assign <= tok && tok <= AssignMod }
 func isIdentifierPart(ch rune) bool { return isIdentifierStart(ch) || isDecimalDigit(ch) }
-func isWhitespace(ch rune) bool     { return ch == ' ' || ch == '\t' || ch == '\n' }
+func isWhitespace(ch rune) bool     { return ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r' }
 func isLetter(ch rune) bool         { return (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') }
 func isDecimalDigit(ch rune) bool   { return (ch >= '0' && ch <= '9') }
 func isLineTerminator(ch rune) bool { return ch == '\n' }
 func hexValue(ch rune) int {
-	if ch >= '0' && ch <= '9' {
+	switch {
+	case ch >= '0' && ch <= '9':
 		return int(ch - '0')
+	case ch >= 'a' && ch <= 'f':
+		return int(ch-'a') + 10
+	case ch >= 'A' && ch <= 'F':
+		return int(ch-'A') + 10
 	}
 	return -1
 }
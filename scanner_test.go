@@ -70,6 +70,174 @@ func TestScanner_Scan(t *testing.T) {
 			{"next", Semicolon},
 			{"next", EOS},
 		}},
+
+		{"operators", "a<=b<<=c>>>=d!=-e--%f^=g&&h|=.5", []tc{
+			{"currentToken", Identifier},
+			{"next", LessThanOrEqual},
+			{"next", Identifier},
+			{"next", AssignShl},
+			{"next", Identifier},
+			{"next", AsignShr},
+			{"next", Identifier},
+			{"next", NotEqual},
+			{"next", Sub},
+			{"next", Identifier},
+			{"next", Dec},
+			{"next", Mod},
+			{"next", Identifier},
+			{"next", AssignBitXor},
+			{"next", Identifier},
+			{"next", And},
+			{"next", Identifier},
+			{"next", AssignBitOr},
+			{"next", Number},
+			{"currentLiteral", ".5"},
+			{"next", EOS},
+		}},
+
+		{"comments", "a // comment\n/* multi\nline */ b /c", []tc{
+			{"currentToken", Identifier},
+			{"next", Identifier},
+			{"currentLiteral", "b"},
+			{"next", Div},
+			{"next", Identifier},
+			{"currentLiteral", "c"},
+			{"next", EOS},
+		}},
+
+		{"unterminated comment", "a /* b", []tc{
+			{"currentToken", Identifier},
+			{"next", Illegal},
+		}},
+
+		{"hex strings", "hex\"deadBEEF\" hex'de_ad' hex\"\"", []tc{
+			{"currentToken", HexStringLiteral},
+			{"currentLiteral", "\xde\xad\xbe\xef"},
+			{"next", HexStringLiteral},
+			{"currentLiteral", "\xde\xad"},
+			{"next", HexStringLiteral},
+			{"currentLiteral", ""},
+			{"next", EOS},
+		}},
+
+		{"hex string odd length", "hex\"abc\"", []tc{
+			{"currentToken", Illegal},
+		}},
+
+		{"hex string leading underscore", "hex\"_ab\"", []tc{
+			{"currentToken", Illegal},
+		}},
+
+		{"hex string trailing underscore", "hex\"ab_\"", []tc{
+			{"currentToken", Illegal},
+		}},
+
+		{"hex string double underscore", "hex\"ab__cd\"", []tc{
+			{"currentToken", Illegal},
+		}},
+
+		{"hex string invalid digit", "hex\"zz\"", []tc{
+			{"currentToken", Illegal},
+		}},
+
+		{"hex string unterminated", "hex\"ab", []tc{
+			{"currentToken", Illegal},
+		}},
+
+		{"hex without string", "hex x", []tc{
+			{"currentToken", Illegal},
+		}},
+
+		{"unicode escapes", "\"\\u00e9\\u20AC\" \"\\xff\"", []tc{
+			{"currentToken", StringLiteral},
+			{"currentLiteral", "\u00e9\u20ac"},
+			{"next", StringLiteral},
+			{"currentLiteral", "\xff"},
+			{"next", EOS},
+		}},
+
+		{"unicode escape too short", "\"\\u12\"", []tc{
+			{"currentToken", Illegal},
+		}},
+
+		{"unicode literal", "unicode\"caf\u00e9 \\u2603\" unicode'x'", []tc{
+			{"currentToken", UnicodeStringLiteral},
+			{"currentLiteral", "caf\u00e9 \u2603"},
+			{"next", UnicodeStringLiteral},
+			{"currentLiteral", "x"},
+			{"next", EOS},
+		}},
+
+		{"non ascii in string", "\"caf\u00e9\"", []tc{
+			{"currentToken", Illegal},
+		}},
+
+		{"control character in string", "\"a\tb\"", []tc{
+			{"currentToken", Illegal},
+		}},
+
+		{"unknown escape", "\"\\q\"", []tc{
+			{"currentToken", Illegal},
+		}},
+
+		{"sized types", "fixed128x18 ufixed64x10 fixed7x1 fixed8x81 ufixed8 fixed8x int8abc bytes33 uint0256 uint256 fixed ufixed", []tc{
+			{"currentToken", FixedMxN},
+			{"next", UFixedMxN},
+			{"next", Identifier},
+			{"next", Identifier},
+			{"next", Identifier},
+			{"next", Identifier},
+			{"next", Identifier},
+			{"next", Identifier},
+			{"next", Identifier},
+			{"next", UIntM},
+			{"next", Fixed},
+			{"next", UFixed},
+			{"next", EOS},
+		}},
+
+		{"number forms", "1_000_000 0xff_ff .5e10 1.5E-3 2e+5 1.foo", []tc{
+			{"currentToken", Number},
+			{"currentLiteral", "1_000_000"},
+			{"next", Number},
+			{"currentLiteral", "0xff_ff"},
+			{"next", Number},
+			{"currentLiteral", ".5e10"},
+			{"next", Number},
+			{"currentLiteral", "1.5E-3"},
+			{"next", Number},
+			{"currentLiteral", "2e+5"},
+			{"next", Number},
+			{"currentLiteral", "1"},
+			{"next", Period},
+			{"next", Identifier},
+			{"next", EOS},
+		}},
+
+		{"trailing underscore", "1_", []tc{{"currentToken", Illegal}}},
+		{"double underscore", "1__0", []tc{{"currentToken", Illegal}}},
+		{"underscore before fraction", "1_.5", []tc{{"currentToken", Illegal}}},
+		{"underscore after period", "1._5", []tc{
+			{"currentToken", Number},
+			{"currentLiteral", "1"},
+			{"next", Period},
+			{"next", Identifier},
+			{"currentLiteral", "_5"},
+		}},
+		{"underscore before exponent", "1_e5", []tc{{"currentToken", Illegal}}},
+		{"underscore after exponent", "1e_5", []tc{{"currentToken", Illegal}}},
+		{"underscore after hex prefix", "0x_ff", []tc{{"currentToken", Illegal}}},
+		{"octal", "012", []tc{{"currentToken", Illegal}}},
+		{"hex exponent", "0x1p5", []tc{{"currentToken", Illegal}}},
+
+		{"assembly operators", "x := y -> :", []tc{
+			{"currentToken", Identifier},
+			{"next", AssemblyAssign},
+			{"next", Identifier},
+			{"next", RightArrow},
+			{"next", Colon},
+			{"next", EOS},
+		}},
 	}
 
 	for _, tt := range tests {
@@ -98,3 +266,145 @@ func TestScanner_Scan(t *testing.T) {
 		}
 	}
 }
+
+// Ensure Scan reports tokens with their byte offsets.
+func TestScanner_ScanSpans(t *testing.T) {
+	src := "uint8 x = /* c */ 0x10;\n// end\nstring s = unicode\"☃\";"
+	type tok struct {
+		tok  Token
+		span Span
+		lit  string
+	}
+	expected := []tok{
+		{UIntM, Span{0, 5}, "uint8"},
+		{Identifier, Span{6, 7}, "x"},
+		{Assign, Span{8, 9}, ""},
+		{Number, Span{18, 22}, "0x10"},
+		{Semicolon, Span{22, 23}, ""},
+		{String, Span{31, 37}, "string"},
+		{Identifier, Span{38, 39}, "s"},
+		{Assign, Span{40, 41}, ""},
+		{UnicodeStringLiteral, Span{42, 54}, "☃"},
+		{Semicolon, Span{54, 55}, ""},
+		{EOS, Span{55, 55}, ""},
+		{EOS, Span{55, 55}, ""},
+	}
+
+	s := NewScanner(strings.NewReader(src))
+	for i, exp := range expected {
+		tok, span, lit := s.Scan()
+		if tok != exp.tok || span != exp.span || lit != exp.lit {
+			t.Errorf("token %d: expected %v got %v", i, exp, []interface{}{tok, span, lit})
+		}
+	}
+}
+
+// Ensure an illegal hex or unicode literal is a single token and scanning
+// resumes after it.
+func TestScanner_IllegalSpans(t *testing.T) {
+	type tok struct {
+		tok  Token
+		span Span
+	}
+	var tests = []struct {
+		name     string
+		source   string
+		expected []tok
+	}{
+		{"hex string odd length", "hex\"000\" x", []tok{
+			{Illegal, Span{0, 8}},
+			{Identifier, Span{9, 10}},
+			{EOS, Span{10, 10}},
+		}},
+		{"hex string invalid digit", "hex'0g' x", []tok{
+			{Illegal, Span{0, 7}},
+			{Identifier, Span{8, 9}},
+			{EOS, Span{9, 9}},
+		}},
+		{"hex string trailing underscore", "hex\"ab_\";", []tok{
+			{Illegal, Span{0, 8}},
+			{Semicolon, Span{8, 9}},
+			{EOS, Span{9, 9}},
+		}},
+		{"unicode escape too short", "unicode\"\\u12\" x", []tok{
+			{Illegal, Span{0, 13}},
+			{Identifier, Span{14, 15}},
+			{EOS, Span{15, 15}},
+		}},
+		{"unicode escape at end of unicode literal", "unicode'a\\u'", []tok{
+			{Illegal, Span{0, 12}},
+			{EOS, Span{12, 12}},
+		}},
+		{"hex escape too short in unicode literal", "unicode\"\\x4\" + y", []tok{
+			{Illegal, Span{0, 12}},
+			{Add, Span{13, 14}},
+			{Identifier, Span{15, 16}},
+			{EOS, Span{16, 16}},
+		}},
+		// like solc regular strings end at the illegal escape
+		{"unicode escape too short in string", "\"\\u12\" x", []tok{
+			{Illegal, Span{0, 5}},
+			{Illegal, Span{5, 8}},
+			{EOS, Span{8, 8}},
+		}},
+		{"hex escape too short in string", "\"\\x4\" + y", []tok{
+			{Illegal, Span{0, 4}},
+			{Illegal, Span{4, 9}},
+			{EOS, Span{9, 9}},
+		}},
+		{"escaped quote after illegal escape", "unicode\"\\u1\\\"\" x", []tok{
+			{Illegal, Span{0, 14}},
+			{Identifier, Span{15, 16}},
+			{EOS, Span{16, 16}},
+		}},
+	}
+
+	for _, tt := range tests {
+		s := NewScanner(strings.NewReader(tt.source))
+		for i, exp := range tt.expected {
+			tok, span, _ := s.Scan()
+			if tok != exp.tok || span != exp.span {
+				t.Errorf("%s: token %d: expected %v %v got %v %v", tt.name, i, exp.tok, exp.span, tok, span)
+			}
+		}
+	}
+}
+
+// Ensure token classification helpers agree with the token table.
+func TestToken_Classification(t *testing.T) {
+	var tests = []struct {
+		tok     Token
+		keyword bool
+		literal bool
+		binary  bool
+		compare bool
+	}{
+		{Contract, true, false, false, false},
+		{SubEther, true, false, false, false},
+		{Unchecked, true, false, false, false},
+		{Try, true, false, false, false},
+		{TrueLiteral, false, true, false, false},
+		{HexStringLiteral, false, true, false, false},
+		{Comma, false, false, true, false},
+		{Or, false, false, true, false},
+		{Add, false, false, true, false},
+		{Exp, false, false, true, false},
+		{LessThan, false, false, false, true},
+		{Identifier, false, false, false, false},
+		{Uint, false, false, false, false},
+	}
+
+	for _, tt := range tests {
+		if IsKeyword(tt.tok) != tt.keyword || IsLiteral(tt.tok) != tt.literal ||
+			IsBinaryOp(tt.tok) != tt.binary || IsCompareOp(tt.tok) != tt.compare {
+			t.Errorf("unexpected classification of '%s'", tt.tok)
+		}
+	}
+
+	if TokenPrecedence(Mul) <= TokenPrecedence(Add) || TokenPrecedence(Identifier) != 0 {
+		t.Error("unexpected precedence")
+	}
+	if Whitespace.String() == "" || Token(-1).String() == "" {
+		t.Error("every token should have a name")
+	}
+}
@@ -0,0 +1,171 @@
+package solparse
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// SyntaxElement is a token or a node of a syntax tree
+type SyntaxElement interface {
+	// Text returns the source text of the element including its trivia
+	Text() string
+}
+
+// SyntaxToken is a token with its source text as written. Whitespace and
+// comments up to and including the end of the line of the token are its
+// trailing trivia, anything before that belongs to the leading trivia of
+// the next token
+type SyntaxToken struct {
+	Token          Token
+	Span           Span
+	Raw            string
+	LeadingTrivia  string
+	TrailingTrivia string
+}
+
+func (t SyntaxToken) Text() string {
+	return t.LeadingTrivia + t.Raw + t.TrailingTrivia
+}
+
+// SyntaxNode groups the tokens that make up an AST node. Node is the AST
+// node, Children are the tokens and nodes in source order
+type SyntaxNode struct {
+	Node     Node
+	Children []SyntaxElement
+}
+
+func (n *SyntaxNode) Text() string {
+	var buf bytes.Buffer
+	n.writeText(&buf)
+	return buf.String()
+}
+
+func (n *SyntaxNode) writeText(buf *bytes.Buffer) {
+	for _, c := range n.Children {
+		if child, ok := c.(*SyntaxNode); ok {
+			child.writeText(buf)
+		} else {
+			buf.WriteString(c.Text())
+		}
+	}
+}
+
+// Tokens returns all tokens below n in source order
+func (n *SyntaxNode) Tokens() (tokens []SyntaxToken) {
+	for _, c := range n.Children {
+		switch c := c.(type) {
+		case *SyntaxNode:
+			tokens = append(tokens, c.Tokens()...)
+		case SyntaxToken:
+			tokens = append(tokens, c)
+		}
+	}
+	return
+}
+
+// ParseSyntaxTree parses a source unit into a lossless syntax tree. The Text
+// of the returned node is identical to the source and its Node is the
+// *SourceUnit. The last child is the EOS token which holds the trivia at
+// the end of the source
+func ParseSyntaxTree(r io.Reader) (*SyntaxNode, error) {
+	p := &Parser{s: NewTriviaScanner(r)}
+	root := &SyntaxNode{}
+	p.syntaxStack = []*SyntaxNode{root}
+
+	su, err := p.Parse()
+	if err != nil {
+		return nil, err
+	}
+	root.Node = su
+	root.Children = append(root.Children, p.s.ScanWithTrivia())
+	return root, nil
+}
+
+// ScanWithTrivia is Scan for scanners created by NewTriviaScanner
+func (s *Scanner) ScanWithTrivia() SyntaxToken {
+	t := s.currentSyntaxToken()
+	s.next()
+	return t
+}
+
+// currentSyntaxToken returns the current token with its trivia. It must be
+// called once for every token, in order
+func (s *Scanner) currentSyntaxToken() SyntaxToken {
+	src := s.src.Bytes()
+	span := s.curTok.span
+	t := SyntaxToken{
+		Token:         s.curTok.token,
+		Span:          span,
+		Raw:           string(src[span.Start:span.End]),
+		LeadingTrivia: string(src[s.triviaEnd:span.Start]),
+	}
+
+	end := span.End
+	if s.curTok.token != EOS {
+		end = trailingTriviaEnd(src, span.End, s.nextTok.span.Start)
+	}
+	t.TrailingTrivia = string(src[span.End:end])
+	s.triviaEnd = end
+	return t
+}
+
+// trailingTriviaEnd returns the end of the trivia in src[start:limit] that
+// still belongs to the line of the token ending at start
+func trailingTriviaEnd(src []byte, start, limit int) int {
+	i := start
+	for i < limit {
+		switch {
+		case src[i] == '\n':
+			return i + 1
+		case bytes.HasPrefix(src[i:limit], []byte("//")):
+			for i < limit && src[i] != '\n' {
+				i++
+			}
+		case bytes.HasPrefix(src[i:limit], []byte("/*")):
+			end := strings.Index(string(src[i+2:limit]), "*/")
+			if end < 0 {
+				return limit
+			}
+			i += end + 4
+		default:
+			i++
+		}
+	}
+	return limit
+}
+
+// markNode starts a syntax node for the AST node the calling parse function
+// returns in *result. The returned function finishes the node and is meant
+// to be deferred. Nodes that only wrap a single other node are dropped
+func (p *Parser) markNode(result interface{}) func() {
+	if p.syntaxStack == nil {
+		return func() {}
+	}
+	n := &SyntaxNode{}
+	p.syntaxStack = append(p.syntaxStack, n)
+
+	return func() {
+		p.syntaxStack = p.syntaxStack[:len(p.syntaxStack)-1]
+		parent := p.syntaxStack[len(p.syntaxStack)-1]
+		if len(n.Children) == 0 {
+			return
+		}
+		if len(n.Children) == 1 {
+			if child, ok := n.Children[0].(*SyntaxNode); ok {
+				parent.Children = append(parent.Children, child)
+				return
+			}
+		}
+
+		v := reflect.ValueOf(result).Elem()
+		for (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && !v.IsNil() {
+			v = v.Elem()
+		}
+		if v.IsValid() && v.CanInterface() {
+			n.Node = v.Interface()
+		}
+		parent.Children = append(parent.Children, n)
+	}
+}
@@ -0,0 +1,93 @@
+package solparse
+
+import (
+	"strings"
+	"testing"
+)
+
+// Ensure syntax trees reprint identical to their source.
+func TestParseSyntaxTree_Lossless(t *testing.T) {
+	var tests = []struct {
+		name   string
+		source string
+	}{
+		{"empty", ""},
+		{"only trivia", "  // nothing here\n/* at all */\n"},
+		{"contract", `// SPDX-License-Identifier: MIT
+contract  Token {
+	uint256 constant total = 1_000e18; // supply
+	/* owner */ address owner;
+
+	modifier onlyOwner() { require(msg.sender == owner, "not owner"); _; }
+
+	function transfer(address to, uint amount) public onlyOwner returns (bool) {
+		unchecked { balances[to] += amount ; }
+		if (amount > 0) { emit(to); } else return false;
+		bytes memory b = hex"00_ff" hex'01';
+		string memory s = unicode"☃";
+		assembly ("memory-safe") {
+			let x := add(1, 2) // inline
+			sstore(0, x)
+		}
+		return true;
+	}
+}
+`},
+		{"crlf and tabs", "contract c {\r\n\tfunction f() {\t}\r\n}\r\n\r\n"},
+		{"free function", "function f(uint a) pure returns (uint) { return a ** 2; }   "},
+	}
+
+	for _, tt := range tests {
+		tree, err := ParseSyntaxTree(strings.NewReader(tt.source))
+		if err != nil {
+			t.Errorf("%s: unexpected error %s", tt.name, err)
+			continue
+		}
+		if text := tree.Text(); text != tt.source {
+			t.Errorf("%s: expected\n%q\ngot\n%q", tt.name, tt.source, text)
+		}
+		if _, ok := tree.Node.(*SourceUnit); !ok {
+			t.Errorf("%s: expected source unit got %T", tt.name, tree.Node)
+		}
+	}
+}
+
+// Ensure trivia is attached to the right tokens.
+func TestParseSyntaxTree_Trivia(t *testing.T) {
+	src := "// doc\ncontract c { /* a */ uint x; // x\n  uint y; }\n"
+	tree, err := ParseSyntaxTree(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []SyntaxToken{
+		{Contract, Span{7, 15}, "contract", "// doc\n", " "},
+		{Identifier, Span{16, 17}, "c", "", " "},
+		{LBrace, Span{18, 19}, "{", "", " /* a */ "},
+		{Uint, Span{28, 32}, "uint", "", " "},
+		{Identifier, Span{33, 34}, "x", "", ""},
+		{Semicolon, Span{34, 35}, ";", "", " // x\n"},
+		{Uint, Span{43, 47}, "uint", "  ", " "},
+		{Identifier, Span{48, 49}, "y", "", ""},
+		{Semicolon, Span{49, 50}, ";", "", " "},
+		{RBrace, Span{51, 52}, "}", "", "\n"},
+		{EOS, Span{53, 53}, "", "", ""},
+	}
+	tokens := tree.Tokens()
+	if len(tokens) != len(expected) {
+		t.Fatalf("expected %d tokens got %d: %+v", len(expected), len(tokens), tokens)
+	}
+	for i, exp := range expected {
+		if tokens[i] != exp {
+			t.Errorf("token %d: expected %+v got %+v", i, exp, tokens[i])
+		}
+	}
+
+	contract := tree.Children[0].(*SyntaxNode)
+	if cd, ok := contract.Node.(ContractDefinition); !ok || cd.Name != "c" {
+		t.Errorf("expected contract node got %+v", contract.Node)
+	}
+	if contract.Children[3].(*SyntaxNode).Text() != "uint x" {
+		t.Errorf("unexpected variable declaration %q", contract.Children[3].Text())
+	}
+}
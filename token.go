@@ -23,6 +23,8 @@ const (
 	Period
 	Conditional
 	Arrow
+	AssemblyAssign
+	RightArrow
 
 	// Assignment
 	Assign
@@ -75,7 +77,9 @@ const (
 	As
 	Assemby
 	Break
+	CallData
 	Const
+	Constructor
 	Continue
 	Contract
 	Default
@@ -106,12 +110,15 @@ const (
 	Storage
 	Struct
 	Throw
+	Unchecked
+	Unicode
 	Using
 	Var
 	While
 
 	// Ether subdenominations
 	SubWei
+	SubGwei
 	SubSzabo
 	SubFinney
 	SubEther
@@ -145,6 +152,8 @@ const (
 	FalseLiteral
 	Number
 	StringLiteral
+	HexStringLiteral
+	UnicodeStringLiteral
 	CommentLiteral
 
 	// Identifiers
@@ -195,6 +204,8 @@ var tokenLiterals = []struct {
 	{".", 0},
 	{"?", 3},
 	{"=>", 0},
+	{":=", 0},
+	{"->", 0},
 
 	{"=", 2},
 	{"|=", 2},
@@ -242,7 +253,9 @@ var tokenLiterals = []struct {
 	{"as", 0},
 	{"assembly", 0},
 	{"break", 0},
+	{"calldata", 0},
 	{"constant", 0},
+	{"constructor", 0},
 	{"continue", 0},
 	{"contract", 0},
 	{"default", 0},
@@ -273,11 +286,14 @@ var tokenLiterals = []struct {
 	{"storage", 0},
 	{"struct", 0},
 	{"throw", 0},
+	{"unchecked", 0},
+	{"unicode", 0},
 	{"using", 0},
 	{"var", 0},
 	{"while", 0},
 
 	{"wei", 0},
+	{"gwei", 0},
 	{"szabo", 0},
 	{"finney", 0},
 	{"ether", 0},
@@ -307,8 +323,10 @@ var tokenLiterals = []struct {
 	{"null", 0},
 	{"true", 0},
 	{"false", 0},
+	{"NUMBER", 0},
 	{"STRINGLIT", 0},
-	{"", 0},
+	{"HEXSTRINGLIT", 0},
+	{"UNICODESTRINGLIT", 0},
 	{"", 0},
 
 	{"IDENT", 0},
@@ -334,22 +352,28 @@ var tokenLiterals = []struct {
 	{"view", 0},
 
 	{"ILLEGAL", 0},
+
+	{"WHITESPACE", 0},
 }
 
+// tokenFromIdentifierOrKeyword returns the token for lit along with the
+// sizes of sized types like uint8 (m = 8) or fixed128x18 (m = 128, n = 18)
 func tokenFromIdentifierOrKeyword(lit string) (tok Token, m int, n int) {
 	posM := firstDigitIndex(lit)
 	if posM != 0 {
 		baseType := lit[0:posM]
-		// TODO handle x
-		posX := len(lit)
-		m = stringToInt(lit[posM:posX]) //parseSize(posM, posX)
+		posX := posM
+		for posX < len(lit) && isDecimalDigit(rune(lit[posX])) {
+			posX++
+		}
+		m = parseSize(lit[posM:posX])
 		tok = keywordByName(baseType)
 		if tok == Bytes {
-			if 0 < m && m <= 32 { //  && posX == len(lit) {
+			if 0 < m && m <= 32 && posX == len(lit) {
 				return BytesM, m, 0
 			}
 		} else if tok == Uint || tok == Int {
-			if 0 < m && m <= 256 && m%8 == 0 { // && positionX == _literal.end())
+			if 0 < m && m <= 256 && m%8 == 0 && posX == len(lit) {
 				if tok == Uint {
 					return UIntM, m, 0
 				} else {
@@ -357,7 +381,16 @@ func tokenFromIdentifierOrKeyword(lit string) (tok Token, m int, n int) {
 				}
 			}
 		} else if tok == UFixed || tok == Fixed {
-			panic("fixed not yet implemented")
+			if posX < len(lit) && lit[posX] == 'x' {
+				n = parseSize(lit[posX+1:])
+				if 8 <= m && m <= 256 && m%8 == 0 && 0 <= n && n <= 80 {
+					if tok == UFixed {
+						return UFixedMxN, m, n
+					} else {
+						return FixedMxN, m, n
+					}
+				}
+			}
 		}
 		return Identifier, 0, 0
 	}
@@ -373,7 +406,12 @@ func keywordByName(n string) Token {
 	return Identifier
 }
 
-func (t Token) String() string { return tokenLiterals[t].Name }
+func (t Token) String() string {
+	if t < 0 || int(t) >= len(tokenLiterals) {
+		return "Token(" + strconv.Itoa(int(t)) + ")"
+	}
+	return tokenLiterals[t].Name
+}
 
 func stringToToken(s string) (tok Token, lit string) {
 	//If the string matches a keyword then return that keyword.
@@ -386,27 +424,63 @@ func stringToToken(s string) (tok Token, lit string) {
 	return Identifier, s
 }
 
-func isVisibilitySpecifier(tok Token) bool {
+func IsVisibilitySpecifier(tok Token) bool {
 	return tok == External || tok == Public || tok == Internal || tok == Private
 }
 
-func isLocationSpecifier(tok Token) bool {
-	return tok == Memory || tok == Storage
+func IsLocationSpecifier(tok Token) bool {
+	return tok == Memory || tok == Storage || tok == CallData
+}
+
+func IsStateMutabilitySpecifier(tok Token) bool {
+	return tok == Const || tok == Payable || tok == View || tok == Pure
+}
+
+func IsSubDenomination(tok Token) bool {
+	return SubWei <= tok && tok <= SubYear
 }
 
-func isUnaryOp(tok Token) bool {
+func IsUnaryOp(tok Token) bool {
 	return (Not <= tok && tok <= Delete) || tok == Add || tok == Sub
 }
 
-func isCountOp(tok Token) bool {
+func IsCountOp(tok Token) bool {
 	return tok == Inc || tok == Dec
 }
 
-func isElementaryTypeName(tok Token) bool {
+func IsElementaryTypeName(tok Token) bool {
 	return Int <= tok && tok < TypesEnd
 }
 
-func tokenPrecedence(tok Token) int { return tokenLiterals[tok].Precedence }
+func IsAssignmentOp(tok Token) bool { return Assign <= tok && tok <= AssignMod }
+
+// IsBinaryOp reports whether tok is an arithmetic, bitwise or logical
+// binary operator or the comma operator, as in solc. Comparisons are
+// reported by IsCompareOp
+func IsBinaryOp(tok Token) bool { return Comma <= tok && tok <= Exp }
+
+func IsCompareOp(tok Token) bool { return Equal <= tok && tok <= GreaterThanOrEqual }
+
+// IsKeyword reports whether tok is a keyword, a unit like ether or days or a
+// keyword reserved for future use. Elementary type names and literals like
+// true are reported by IsElementaryTypeName and IsLiteral
+func IsKeyword(tok Token) bool {
+	return (Anonymous <= tok && tok <= SubYear) || (Abstract <= tok && tok <= View)
+}
+
+// IsLiteral reports whether tok is a boolean, number or string literal
+func IsLiteral(tok Token) bool { return NullLiteral <= tok && tok <= UnicodeStringLiteral }
+
+// TokenPrecedence returns the binding strength of an operator, higher binds
+// tighter. Comma is 1, assignments 2, the conditional '?' 3 and binary and
+// compare operators range from 4 for || to 14 for **. It is 0 for all other
+// tokens
+func TokenPrecedence(tok Token) int {
+	if tok < 0 || int(tok) >= len(tokenLiterals) {
+		return 0
+	}
+	return tokenLiterals[tok].Precedence
+}
 
 func firstDigitIndex(s string) int {
 	for k, v := range s {
@@ -417,11 +491,20 @@ func firstDigitIndex(s string) int {
 	return 0
 }
 
-func stringToInt(s string) (i int) {
-	var err error
-	i, err = strconv.Atoi(s)
+// parseSize returns the value of the type size s or -1 if it is not a
+// number of at most three digits
+func parseSize(s string) int {
+	if len(s) == 0 || len(s) > 3 {
+		return -1
+	}
+	for _, c := range s {
+		if !isDecimalDigit(c) {
+			return -1
+		}
+	}
+	i, err := strconv.Atoi(s)
 	if err != nil {
-		panic(err)
+		return -1
 	}
-	return
+	return i
 }
@@ -0,0 +1,595 @@
+package solparse
+
+import (
+	"errors"
+	"fmt"
+)
+
+// InlineAssembly is an assembly block inside a function body. Dialect is
+// the optional "evmasm" string and Flags the strings in the parenthesis,
+// e.g. assembly ("memory-safe") { ... }
+type InlineAssembly struct {
+	Dialect string
+	Flags   []string
+	Body    YulBlock
+}
+
+type YulBlock struct {
+	Statements []Node
+}
+
+// YulTypedName is a variable or parameter name with an optional type, e.g.
+// x or x:u256
+type YulTypedName struct {
+	Name string
+	Type string
+}
+
+// YulVariableDeclaration represents let a, b := f(), Value is nil if the
+// variables are not initialized
+type YulVariableDeclaration struct {
+	Variables []YulTypedName
+	Value     Expression
+}
+
+// YulAssignment represents a, b := f()
+type YulAssignment struct {
+	VariableNames []YulIdentifier
+	Value         Expression
+}
+
+type YulIf struct {
+	Condition Expression
+	Body      YulBlock
+}
+
+type YulSwitch struct {
+	Expression Expression
+	Cases      []YulCase
+}
+
+// YulCase is a case of a switch statement, Value is nil for the default case
+type YulCase struct {
+	Value *YulLiteral
+	Body  YulBlock
+}
+
+type YulForLoop struct {
+	Pre       YulBlock
+	Condition Expression
+	Post      YulBlock
+	Body      YulBlock
+}
+
+type YulFunctionDefinition struct {
+	Name            string
+	Parameters      []YulTypedName
+	ReturnVariables []YulTypedName
+	Body            YulBlock
+}
+
+type YulBreak struct{}
+
+type YulContinue struct{}
+
+type YulLeave struct{}
+
+// YulExpressionStatement is a function call whose result is discarded
+type YulExpressionStatement struct {
+	Expression Expression
+}
+
+// YulFunctionCall is a call of a builtin like add(x, 1) or of a function
+// defined in assembly
+type YulFunctionCall struct {
+	FunctionName string
+	Arguments    []Expression
+}
+
+// YulIdentifier refers to a yul variable or to a solidity variable, the
+// latter possibly with a suffix like x.slot
+type YulIdentifier struct {
+	Name string
+}
+
+// YulLiteral is a number, string, hex string or boolean constant with an
+// optional type, e.g. 1:u256
+type YulLiteral struct {
+	Kind  LiteralKind
+	Value string
+	Type  string
+}
+
+// yulScope tracks where break, continue, leave and function definitions
+// are allowed
+type yulScope struct {
+	insideFunction bool
+	insideForBody  bool
+	insideForInit  bool
+}
+
+func (p *Parser) parseInlineAssembly() (a InlineAssembly, err error) {
+	defer p.markNode(&a)()
+	err = p.expectToken(Assemby)
+	if err != nil {
+		return
+	}
+
+	if p.currentToken() == StringLiteral {
+		a.Dialect = p.getLiteralAndAdvance()
+		if a.Dialect != "evmasm" {
+			return a, errors.New("Only \"evmasm\" supported")
+		}
+	}
+
+	if p.currentToken() == LParen {
+		p.next()
+		for {
+			if p.currentToken() != StringLiteral {
+				return a, fmt.Errorf("Expected assembly flag, got '%s'", p.currentToken())
+			}
+			a.Flags = append(a.Flags, p.getLiteralAndAdvance())
+			if p.currentToken() != Comma {
+				break
+			}
+			p.next()
+		}
+		err = p.expectToken(RParen)
+		if err != nil {
+			return
+		}
+	}
+
+	a.Body, err = p.parseYulBlock(yulScope{})
+	return
+}
+
+func (p *Parser) parseYulBlock(scope yulScope) (b YulBlock, err error) {
+	err = p.expectToken(LBrace)
+	if err != nil {
+		return
+	}
+
+	b.Statements = append(b.Statements, p.takeComments()...)
+	for p.currentToken() != RBrace {
+		stmt, err := p.parseYulStatement(scope)
+		if err != nil {
+			return b, err
+		}
+		b.Statements = append(b.Statements, stmt)
+		b.Statements = append(b.Statements, p.takeComments()...)
+	}
+
+	err = p.expectToken(RBrace)
+	return b, err
+}
+
+func (p *Parser) parseYulStatement(scope yulScope) (n Node, err error) {
+	defer p.markNode(&n)()
+	switch p.currentToken() {
+	case LBrace:
+		return p.parseYulBlock(scope)
+	case Function:
+		if scope.insideForInit {
+			return n, errors.New("Functions cannot be defined inside a for-loop init block")
+		}
+		return p.parseYulFunctionDefinition()
+	case Let:
+		return p.parseYulVariableDeclaration()
+	case If:
+		p.next()
+		s := YulIf{}
+		s.Condition, err = p.parseYulExpression()
+		if err != nil {
+			return s, err
+		}
+		s.Body, err = p.parseYulBlock(scope)
+		return s, err
+	case Switch:
+		return p.parseYulSwitch(scope)
+	case For:
+		return p.parseYulForLoop(scope)
+	case Break, Continue:
+		tok := p.currentToken()
+		if !scope.insideForBody {
+			return n, fmt.Errorf("Keyword '%s' needs to be inside a for-loop body", tok)
+		}
+		p.next()
+		if tok == Break {
+			return YulBreak{}, nil
+		}
+		return YulContinue{}, nil
+	}
+
+	if p.currentToken() == Identifier && p.currentLiteral() == "leave" {
+		if !scope.insideFunction {
+			return n, errors.New("Keyword 'leave' can only be used inside a function")
+		}
+		p.next()
+		return YulLeave{}, nil
+	}
+
+	if !p.atYulIdentifier() {
+		e, err := p.parseYulExpression()
+		if err != nil {
+			return n, err
+		}
+		if _, ok := e.(YulFunctionCall); !ok {
+			return n, errors.New("Call or assignment expected")
+		}
+		return YulExpressionStatement{Expression: e}, nil
+	}
+
+	// identifier: either the start of an assignment or a function call
+	name := p.parseYulIdentifier()
+	if p.currentToken() == LParen {
+		e, err := p.parseYulFunctionCall(name)
+		return YulExpressionStatement{Expression: e}, err
+	}
+
+	a := YulAssignment{VariableNames: []YulIdentifier{{Name: name}}}
+	for p.currentToken() == Comma {
+		p.next()
+		if !p.atYulIdentifier() {
+			return a, fmt.Errorf("Expected identifier, got '%s'", p.currentToken())
+		}
+		a.VariableNames = append(a.VariableNames, YulIdentifier{Name: p.parseYulIdentifier()})
+	}
+	if p.currentToken() != AssemblyAssign {
+		return a, errors.New("Call or assignment expected")
+	}
+	p.next()
+	a.Value, err = p.parseYulExpression()
+	return a, err
+}
+
+func (p *Parser) parseYulFunctionDefinition() (f YulFunctionDefinition, err error) {
+	err = p.expectToken(Function)
+	if err != nil {
+		return
+	}
+	if !p.atYulIdentifier() {
+		return f, fmt.Errorf("Expected function name, got '%s'", p.currentToken())
+	}
+	f.Name = p.parseYulIdentifier()
+
+	err = p.expectToken(LParen)
+	if err != nil {
+		return
+	}
+	if p.currentToken() != RParen {
+		f.Parameters, err = p.parseYulTypedNameList()
+		if err != nil {
+			return
+		}
+	}
+	err = p.expectToken(RParen)
+	if err != nil {
+		return
+	}
+
+	if p.currentToken() == RightArrow {
+		p.next()
+		f.ReturnVariables, err = p.parseYulTypedNameList()
+		if err != nil {
+			return
+		}
+	}
+
+	// break and continue do not cross function boundaries
+	f.Body, err = p.parseYulBlock(yulScope{insideFunction: true})
+	return
+}
+
+func (p *Parser) parseYulVariableDeclaration() (v YulVariableDeclaration, err error) {
+	err = p.expectToken(Let)
+	if err != nil {
+		return
+	}
+	v.Variables, err = p.parseYulTypedNameList()
+	if err != nil {
+		return
+	}
+	if p.currentToken() == AssemblyAssign {
+		p.next()
+		v.Value, err = p.parseYulExpression()
+	}
+	return
+}
+
+func (p *Parser) parseYulSwitch(scope yulScope) (s YulSwitch, err error) {
+	err = p.expectToken(Switch)
+	if err != nil {
+		return
+	}
+	s.Expression, err = p.parseYulExpression()
+	if err != nil {
+		return
+	}
+
+	for p.currentToken() == Case {
+		p.next()
+		c := YulCase{}
+		l, err := p.parseYulLiteral()
+		if err != nil {
+			return s, err
+		}
+		c.Value = &l
+		c.Body, err = p.parseYulBlock(scope)
+		if err != nil {
+			return s, err
+		}
+		s.Cases = append(s.Cases, c)
+	}
+
+	if p.currentToken() == Default {
+		p.next()
+		c := YulCase{}
+		c.Body, err = p.parseYulBlock(scope)
+		if err != nil {
+			return
+		}
+		s.Cases = append(s.Cases, c)
+		if p.currentToken() == Case {
+			return s, errors.New("Case not allowed after default case")
+		}
+	}
+
+	if len(s.Cases) == 0 {
+		return s, errors.New("Switch statement without any cases")
+	}
+	return
+}
+
+func (p *Parser) parseYulForLoop(scope yulScope) (f YulForLoop, err error) {
+	err = p.expectToken(For)
+	if err != nil {
+		return
+	}
+
+	init := scope
+	init.insideForBody = false
+	init.insideForInit = true
+	f.Pre, err = p.parseYulBlock(init)
+	if err != nil {
+		return
+	}
+	f.Condition, err = p.parseYulExpression()
+	if err != nil {
+		return
+	}
+
+	post := scope
+	post.insideForBody = false
+	f.Post, err = p.parseYulBlock(post)
+	if err != nil {
+		return
+	}
+
+	body := scope
+	body.insideForBody = true
+	f.Body, err = p.parseYulBlock(body)
+	return
+}
+
+func (p *Parser) parseYulExpression() (e Expression, err error) {
+	if p.atYulIdentifier() {
+		name := p.parseYulIdentifier()
+		if p.currentToken() == LParen {
+			return p.parseYulFunctionCall(name)
+		}
+		return YulIdentifier{Name: name}, nil
+	}
+	return p.parseYulLiteral()
+}
+
+func (p *Parser) parseYulFunctionCall(name string) (c YulFunctionCall, err error) {
+	c.FunctionName = name
+	err = p.expectToken(LParen)
+	if err != nil {
+		return
+	}
+	for p.currentToken() != RParen {
+		arg, err := p.parseYulExpression()
+		if err != nil {
+			return c, err
+		}
+		c.Arguments = append(c.Arguments, arg)
+		if p.currentToken() == RParen {
+			break
+		}
+		err = p.expectToken(Comma)
+		if err != nil {
+			return c, err
+		}
+	}
+	err = p.expectToken(RParen)
+	return
+}
+
+func (p *Parser) parseYulLiteral() (l YulLiteral, err error) {
+	switch p.currentToken() {
+	case Number:
+		l.Kind = NumberLiteralKind
+	case StringLiteral:
+		l.Kind = StringLiteralKind
+	case HexStringLiteral:
+		l.Kind = HexStringLiteralKind
+	case TrueLiteral, FalseLiteral:
+		l.Kind = BoolLiteralKind
+	default:
+		return l, fmt.Errorf("Expected literal, got '%s'", p.currentToken())
+	}
+	l.Value = p.getLiteralAndAdvance()
+
+	if p.currentToken() == Colon {
+		p.next()
+		if !p.atYulIdentifier() {
+			return l, fmt.Errorf("Expected type name, got '%s'", p.currentToken())
+		}
+		l.Type = p.parseYulIdentifier()
+	}
+	return
+}
+
+func (p *Parser) parseYulTypedNameList() (names []YulTypedName, err error) {
+	for {
+		if !p.atYulIdentifier() {
+			return names, fmt.Errorf("Expected identifier, got '%s'", p.currentToken())
+		}
+		n := YulTypedName{Name: p.parseYulIdentifier()}
+		if p.currentToken() == Colon {
+			p.next()
+			if !p.atYulIdentifier() {
+				return names, fmt.Errorf("Expected type name, got '%s'", p.currentToken())
+			}
+			n.Type = p.parseYulIdentifier()
+		}
+		names = append(names, n)
+		if p.currentToken() != Comma {
+			return
+		}
+		p.next()
+	}
+}
+
+// atYulIdentifier reports whether the current token can be used as a yul
+// identifier. Most solidity keywords are plain identifiers in yul, e.g. the
+// builtins return and byte
+func (p *Parser) atYulIdentifier() bool {
+	switch p.currentToken() {
+	case Identifier:
+		return true
+	case Number, StringLiteral, HexStringLiteral, UnicodeStringLiteral, TrueLiteral, FalseLiteral,
+		Function, Let, If, Switch, Case, Default, For, Break, Continue:
+		return false
+	}
+	lit := p.currentLiteral()
+	return lit != "" && isIdentifierStart(rune(lit[0]))
+}
+
+// parseYulIdentifier returns the current identifier joined with any
+// following .member parts, e.g. x.slot
+func (p *Parser) parseYulIdentifier() string {
+	name := p.getLiteralAndAdvance()
+	for p.currentToken() == Period && p.s.peekNextToken() == Identifier {
+		p.next()
+		name += "." + p.getLiteralAndAdvance()
+	}
+	return name
+}
+
+// YulObject is the top level of a standalone yul source. Code is the code
+// block of the object, Objects and Data are its sub objects and data
+// sections in source order
+type YulObject struct {
+	Name    string
+	Code    YulBlock
+	Objects []YulObject
+	Data    []YulData
+}
+
+// YulData is a named data section, e.g. data "x" hex"0011". Value holds the
+// decoded bytes of hex literals
+type YulData struct {
+	Name  string
+	Value string
+	IsHex bool
+}
+
+// ParseYul parses a standalone yul source, either an object or a single
+// block which is treated as the code of an object named "object"
+func (p *Parser) ParseYul() (o *YulObject, err error) {
+	if p.currentToken() == LBrace {
+		o = &YulObject{Name: "object"}
+		o.Code, err = p.parseYulBlock(yulScope{})
+	} else {
+		var obj YulObject
+		obj, err = p.parseYulObject()
+		o = &obj
+	}
+	if err != nil {
+		return nil, err
+	}
+	if p.currentToken() != EOS {
+		return nil, fmt.Errorf("Expected end of source, got '%s'", p.currentToken())
+	}
+	return o, nil
+}
+
+func (p *Parser) parseYulObject() (o YulObject, err error) {
+	err = p.expectYulKeyword("object")
+	if err != nil {
+		return
+	}
+	if p.currentToken() != StringLiteral {
+		return o, fmt.Errorf("Expected object name, got '%s'", p.currentToken())
+	}
+	o.Name = p.getLiteralAndAdvance()
+
+	err = p.expectToken(LBrace)
+	if err != nil {
+		return
+	}
+	err = p.expectYulKeyword("code")
+	if err != nil {
+		return
+	}
+	o.Code, err = p.parseYulBlock(yulScope{})
+	if err != nil {
+		return
+	}
+
+	for p.currentToken() != RBrace {
+		switch {
+		case p.currentToken() == Identifier && p.currentLiteral() == "object":
+			sub, err := p.parseYulObject()
+			if err != nil {
+				return o, err
+			}
+			o.Objects = append(o.Objects, sub)
+		case p.currentToken() == Identifier && p.currentLiteral() == "data":
+			d, err := p.parseYulData()
+			if err != nil {
+				return o, err
+			}
+			o.Data = append(o.Data, d)
+		default:
+			return o, fmt.Errorf("Expected object, data or '}', got '%s'", p.currentToken())
+		}
+	}
+
+	err = p.expectToken(RBrace)
+	return
+}
+
+func (p *Parser) parseYulData() (d YulData, err error) {
+	err = p.expectYulKeyword("data")
+	if err != nil {
+		return
+	}
+	if p.currentToken() != StringLiteral {
+		return d, fmt.Errorf("Expected data name, got '%s'", p.currentToken())
+	}
+	d.Name = p.getLiteralAndAdvance()
+
+	switch p.currentToken() {
+	case HexStringLiteral:
+		d.IsHex = true
+	case StringLiteral:
+	default:
+		return d, fmt.Errorf("Expected string or hex literal, got '%s'", p.currentToken())
+	}
+	d.Value = p.getLiteralAndAdvance()
+	return
+}
+
+// expectYulKeyword consumes an identifier that has a special meaning in yul
+// objects, e.g. object or code
+func (p *Parser) expectYulKeyword(keyword string) error {
+	if p.currentToken() != Identifier || p.currentLiteral() != keyword {
+		return fmt.Errorf("Expected '%s', got '%s'", keyword, p.currentLiteral())
+	}
+	p.next()
+	return nil
+}